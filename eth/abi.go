@@ -21,15 +21,8 @@ package eth
 import (
 	"context"
 	"fmt"
-	"io"
-	"os"
 	"path/filepath"
 
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/mount"
-	dockersdk "github.com/docker/docker/client"
-	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/paulwizviz/narwhal/shared"
 )
 
@@ -40,78 +33,73 @@ const (
 
 // ABIGen is an abstraction of Ethereum ABIGen docker client
 type ABIGen interface {
-	// GenGoBinding generates Go binding
+	// Prepare makes the tool image available locally as per the pull
+	// policy. Tool calls prepare the image on first use, so it only needs
+	// calling to pull ahead of time, e.g. at start up under a deadline.
+	Prepare(ctx context.Context) error
+	// GenGoBinding generates Go binding. If abigen exits with a non-zero
+	// status, a *shared.ToolError with Op shared.OpRunTool is returned with the container ID
 	GenGoBinding(ctx context.Context, name string, abiPath string, outPath string, pkgName string, localType string) (string, error)
-	// RemoveContainer remove container for a given ID
+	// RemoveContainer remove a stopped container for a given ID. Containers are
+	// removed after each run unless the client is configured WithKeepContainer.
 	RemoveContainer(ctx context.Context, containerID string) error
 	// RemoveContainerForce remove container for ID with no exception
 	RemoveContainerForce(ctx context.Context, containerID string) error
 }
 
 type abigen struct {
-	cli          *dockersdk.Client
-	osPlatform   string
-	archPlatform string
-	image        string
+	cli       shared.Engine
+	runner    *shared.Runner
+	toolImage *shared.ToolImage
+	platform  shared.DockerPlatformConfig
+	image     string
 }
 
-func (a abigen) GenGoBinding(ctx context.Context, name string, abiPath string, outPath string, pkgName string, localType string) (string, error) {
-	return generateGoBinding(ctx, a.cli, a.image, name, a.osPlatform, a.archPlatform, abiPath, outPath, pkgName, localType)
+func (a abigen) Prepare(ctx context.Context) error {
+	if err := a.toolImage.Prepare(ctx); err != nil {
+		return shared.PullImageError(err, "eth", "Prepare")
+	}
+	return nil
 }
 
-func generateGoBinding(ctx context.Context, client *dockersdk.Client, image string, name string, platformOS string, arch string, abiPath string, outPath string, pkgName string, localType string) (string, error) {
-
-	platform := &v1.Platform{
-		OS:           platformOS,
-		Architecture: arch,
+func (a abigen) GenGoBinding(ctx context.Context, name string, abiPath string, outPath string, pkgName string, localType string) (string, error) {
+	if err := a.Prepare(ctx); err != nil {
+		return "", err
 	}
+	return generateGoBinding(ctx, a.runner, a.image, name, a.platform, abiPath, outPath, pkgName, localType)
+}
+
+func generateGoBinding(ctx context.Context, runner *shared.Runner, image string, name string, platform shared.DockerPlatformConfig, abiPath string, outPath string, pkgName string, localType string) (string, error) {
 
 	localABIFolder := "/opt/abi"
 	localBindingFolder := "/opt/binding"
 
-	containConfig := &container.Config{
-		Image: image,
-		Cmd:   []string{"abigen", "--abi", fmt.Sprintf("%s/%s.abi", localABIFolder, localType), "--bin", fmt.Sprintf("/opt/abi/%s.bin", localType), "--pkg", pkgName, "--type", localType, "--out", fmt.Sprintf("/opt/binding/%s/%s.go", pkgName, localType)},
-	}
-
-	hostConfig := &container.HostConfig{
-		Mounts: []mount.Mount{
+	result, err := runner.Run(ctx, shared.RunSpec{
+		Package:  "eth",
+		Func:     "generateGoBinding",
+		Tool:     "abigen",
+		Name:     name,
+		Image:    image,
+		Platform: platform,
+		Cmd:      []string{"abigen", "--abi", fmt.Sprintf("%s/%s.abi", localABIFolder, localType), "--bin", fmt.Sprintf("%s/%s.bin", localABIFolder, localType), "--pkg", pkgName, "--type", localType, "--out", fmt.Sprintf("%s/%s/%s.go", localBindingFolder, pkgName, localType)},
+		Mounts: []shared.Mount{
 			{
-				Type:   mount.TypeBind,
-				Source: filepath.Join(abiPath, fmt.Sprintf("%s.abi", localType)),
-				Target: fmt.Sprintf("%s/%s", localABIFolder, fmt.Sprintf("%s.abi", localType)),
+				Source:   filepath.Join(abiPath, fmt.Sprintf("%s.abi", localType)),
+				Target:   fmt.Sprintf("%s/%s.abi", localABIFolder, localType),
+				ReadOnly: true,
 			},
 			{
-				Type:   mount.TypeBind,
-				Source: filepath.Join(abiPath, fmt.Sprintf("%s.bin", localType)),
-				Target: fmt.Sprintf("%s/%s", localABIFolder, fmt.Sprintf("%s.bin", localType)),
+				Source:   filepath.Join(abiPath, fmt.Sprintf("%s.bin", localType)),
+				Target:   fmt.Sprintf("%s/%s.bin", localABIFolder, localType),
+				ReadOnly: true,
 			},
 			{
-				Type:   mount.TypeBind,
 				Source: outPath,
 				Target: fmt.Sprintf("%s/%s", localBindingFolder, pkgName),
 			},
 		},
-	}
-
-	resp, err := client.ContainerCreate(ctx, containConfig, hostConfig, nil, platform, name)
-	if err != nil {
-		return "", shared.CreateContainerErr(err, "eth", "generateGoBinding")
-	}
-
-	if err := client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", shared.StartContainerErr(err, "eth", "generateGoBinding")
-	}
-
-	out, err := client.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Timestamps: true})
-	if err != nil {
-		return "", shared.ContainerLogErr(err, "eth", "generateGoBinding")
-	}
-	defer out.Close()
-
-	io.Copy(os.Stdout, out)
-
-	return resp.ID, nil
+	})
+	return result.ContainerID, err
 }
 
 func (a abigen) RemoveContainer(ctx context.Context, containerID string) error {
@@ -122,33 +110,56 @@ func (a abigen) RemoveContainerForce(ctx context.Context, containerID string) er
 	return shared.RemoveContainerForce(ctx, a.cli, containerID)
 }
 
+// NewABIGen instantiate an ethereum/client-go client. Unless configured otherwise
+// by opts, it uses ethereum/client-go:alltools-stable for Linux/amd64 platform, a
+// Docker client configured from the environment and pulls the image only if it
+// is not present. The image is prepared on first use; see ABIGen.Prepare.
+func NewABIGen(opts ...shared.Option) (ABIGen, error) {
+	cfg := shared.NewConfig(append([]shared.Option{
+		shared.WithImage(fmt.Sprintf("%s:%s", EthereumGethToolImage, "alltools-stable")),
+		shared.WithPlatform(shared.PlatformLinuxAMD64()),
+	}, opts...)...)
+
+	if cfg.Engine == nil {
+		cli, err := shared.NewDockerEngine()
+		if err != nil {
+			return nil, shared.InstantiateClientErr(err, "eth", "NewABIGen")
+		}
+		cfg.Engine = cli
+	}
+
+	return &abigen{
+		cli:       cfg.Engine,
+		runner:    shared.NewRunner(cfg.Engine, opts...),
+		toolImage: shared.NewToolImage(cfg),
+		platform:  cfg.Platform,
+		image:     cfg.Image,
+	}, nil
+}
+
 // NewDefaultProtoc instantiate an ethereum/client-go client for Linux/amd64 platform
 //
 // Arguments:
 //
-// - imgTag is the tag associated with ethereum/client-go
-func NewDefaultProtoc(imgTag string) (ABIGen, error) {
-
-	cli, err := dockersdk.NewClientWithOpts(dockersdk.FromEnv, dockersdk.WithAPIVersionNegotiation())
-	if err != nil {
-		return nil, shared.InstantiateClientErr(err, "eth", "NewDefaultProtoc")
-	}
-	gethToolImage := fmt.Sprintf("%s:%s", EthereumGethToolImage, imgTag)
-
-	p := shared.PlatformLinuxAMD64()
-	reader, err := cli.ImagePull(context.Background(), gethToolImage, image.PullOptions{
-		Platform: fmt.Sprintf("%s/%s", p.OS, p.Arch),
-	})
-	if err != nil {
-		return nil, shared.PullImageError(err, "eth", "NewDefaultProtoc")
-	}
-	defer reader.Close()
-	io.Copy(os.Stdout, reader)
+// - imgTag is the tag associated with ethereum/client-go, or a digest sha256:...
+// - opts configure the client further as per NewABIGen
+func NewDefaultProtoc(imgTag string, opts ...shared.Option) (ABIGen, error) {
+	return NewABIGen(append([]shared.Option{
+		shared.WithImage(shared.ImageRef(EthereumGethToolImage, imgTag)),
+	}, opts...)...)
+}
 
-	return &abigen{
-		cli:          cli,
-		osPlatform:   p.OS,
-		archPlatform: p.Arch,
-		image:        gethToolImage,
-	}, nil
+// NewABIGenWithEngine instantiate an ethereum/client-go client for Linux/amd64
+// platform backed by the given container engine
+//
+// Arguments:
+//
+// - engine is a Docker client or a fake engine
+// - imgTag is the tag associated with ethereum/client-go, or a digest sha256:...
+// - opts configure the client further as per NewABIGen
+func NewABIGenWithEngine(engine shared.Engine, imgTag string, opts ...shared.Option) (ABIGen, error) {
+	return NewABIGen(append([]shared.Option{
+		shared.WithClient(engine),
+		shared.WithImage(shared.ImageRef(EthereumGethToolImage, imgTag)),
+	}, opts...)...)
 }
@@ -0,0 +1,308 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/paulwizviz/narwhal/shared"
+)
+
+// Metadata hash methods appended to the bytecode
+const (
+	MetadataHashIPFS  = "ipfs"
+	MetadataHashBzzr1 = "bzzr1"
+	MetadataHashNone  = "none"
+)
+
+// Revert string settings
+const (
+	RevertStringsDefault      = "default"
+	RevertStringsStrip        = "strip"
+	RevertStringsDebug        = "debug"
+	RevertStringsVerboseDebug = "verboseDebug"
+)
+
+var (
+	// ErrInvalidCompileOptions represents CompileOptions that are
+	// inconsistent or not supported by the solc version in use
+	ErrInvalidCompileOptions = errors.New("invalid compile options")
+)
+
+// CompileOptions represents settings of a Solidity compilation. The zero
+// value leaves solc's defaults in place.
+type CompileOptions struct {
+	// Optimize enables the optimizer
+	Optimize bool
+	// OptimizeRuns is the number of times code is expected to run, which
+	// the optimizer trades deployment cost against. Requires Optimize.
+	OptimizeRuns int
+	// ViaIR compiles through the Yul intermediate representation.
+	// Requires solc 0.8.13 or later.
+	ViaIR bool
+	// MetadataHash is one of MetadataHashIPFS, MetadataHashBzzr1 or
+	// MetadataHashNone. Requires solc 0.6.0 or later.
+	MetadataHash string
+	// NoCBORMetadata omits the CBOR encoded metadata from the bytecode.
+	// Requires solc 0.8.18 or later.
+	NoCBORMetadata bool
+	// RevertStrings is one of RevertStringsDefault, RevertStringsStrip,
+	// RevertStringsDebug or RevertStringsVerboseDebug. Requires solc 0.6.3
+	// or later.
+	RevertStrings string
+}
+
+// Validate checks that the options are consistent and supported by solc
+// version, e.g. 0.8.28. Version checks are skipped when version is empty.
+func (o CompileOptions) Validate(version string) error {
+	if o.OptimizeRuns < 0 {
+		return fmt.Errorf("%w: negative optimize runs", ErrInvalidCompileOptions)
+	}
+	if o.OptimizeRuns > 0 && !o.Optimize {
+		return fmt.Errorf("%w: optimize runs require optimize", ErrInvalidCompileOptions)
+	}
+	switch o.MetadataHash {
+	case "", MetadataHashIPFS, MetadataHashBzzr1, MetadataHashNone:
+	default:
+		return fmt.Errorf("%w: metadata hash %s", ErrInvalidCompileOptions, o.MetadataHash)
+	}
+	if o.NoCBORMetadata && o.MetadataHash != "" && o.MetadataHash != MetadataHashNone {
+		return fmt.Errorf("%w: metadata hash %s requires CBOR metadata", ErrInvalidCompileOptions, o.MetadataHash)
+	}
+	switch o.RevertStrings {
+	case "", RevertStringsDefault, RevertStringsStrip, RevertStringsDebug, RevertStringsVerboseDebug:
+	default:
+		return fmt.Errorf("%w: revert strings %s", ErrInvalidCompileOptions, o.RevertStrings)
+	}
+
+	if version == "" {
+		return nil
+	}
+	requirements := []struct {
+		set     bool
+		option  string
+		version string
+	}{
+		{o.ViaIR, "via IR", "0.8.13"},
+		{o.MetadataHash != "", "metadata hash", "0.6.0"},
+		{o.NoCBORMetadata, "no CBOR metadata", "0.8.18"},
+		{o.RevertStrings != "", "revert strings", "0.6.3"},
+	}
+	for _, r := range requirements {
+		if !r.set {
+			continue
+		}
+		older, err := versionLess(version, r.version)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidCompileOptions, err)
+		}
+		if older {
+			return fmt.Errorf("%w: %s requires solc %s or later, got %s", ErrInvalidCompileOptions, r.option, r.version, version)
+		}
+	}
+	return nil
+}
+
+// versioned reports whether any option depends on the solc version
+func (o CompileOptions) versioned() bool {
+	return o.ViaIR || o.MetadataHash != "" || o.NoCBORMetadata || o.RevertStrings != ""
+}
+
+// args returns the options as solc command line arguments
+func (o CompileOptions) args() []string {
+	var args []string
+	if o.Optimize {
+		args = append(args, "--optimize")
+	}
+	if o.OptimizeRuns > 0 {
+		args = append(args, "--optimize-runs", strconv.Itoa(o.OptimizeRuns))
+	}
+	if o.ViaIR {
+		args = append(args, "--via-ir")
+	}
+	if o.MetadataHash != "" {
+		args = append(args, "--metadata-hash", o.MetadataHash)
+	}
+	if o.NoCBORMetadata {
+		args = append(args, "--no-cbor-metadata")
+	}
+	if o.RevertStrings != "" {
+		args = append(args, "--revert-strings", o.RevertStrings)
+	}
+	return args
+}
+
+// applyTo sets the options in Standard JSON settings
+func (o CompileOptions) applyTo(settings *StandardJSONSettings) {
+	if o.Optimize {
+		settings.Optimizer = &StandardJSONOptimizer{Enabled: true, Runs: o.OptimizeRuns}
+	}
+	if o.ViaIR {
+		settings.ViaIR = true
+	}
+	if o.MetadataHash != "" || o.NoCBORMetadata {
+		if settings.Metadata == nil {
+			settings.Metadata = &StandardJSONMetadata{}
+		}
+		settings.Metadata.BytecodeHash = o.MetadataHash
+		if o.NoCBORMetadata {
+			appendCBOR := false
+			settings.Metadata.AppendCBOR = &appendCBOR
+		}
+	}
+	if o.RevertStrings != "" {
+		settings.Debug = &StandardJSONDebug{RevertStrings: o.RevertStrings}
+	}
+}
+
+// versionLess reports whether semantic version a is older than b
+func versionLess(a string, b string) (bool, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return false, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return false, err
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return va[i] < vb[i], nil
+		}
+	}
+	return false, nil
+}
+
+func parseVersion(v string) ([3]int, error) {
+	var parsed [3]int
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return parsed, fmt.Errorf("invalid version %s", v)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid version %s", v)
+		}
+		parsed[i] = n
+	}
+	return parsed, nil
+}
+
+var versionPattern = regexp.MustCompile(`Version: (\d+\.\d+\.\d+)`)
+
+// solcVersion caches the version of the solc in a client's image
+type solcVersion struct {
+	mu      sync.Mutex
+	version string
+	pending *versionCall
+}
+
+// versionCall represents a version lookup in progress, which concurrent
+// callers wait for
+type versionCall struct {
+	done    chan struct{}
+	version string
+	err     error
+}
+
+func (s solc) Version(ctx context.Context) (string, error) {
+	for {
+		s.version.mu.Lock()
+		if version := s.version.version; version != "" {
+			s.version.mu.Unlock()
+			return version, nil
+		}
+		if call := s.version.pending; call != nil {
+			s.version.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-call.done:
+			}
+			// The caller that looked it up gave up, which says nothing of
+			// this one, so try again
+			if errors.Is(call.err, shared.ErrToolTimeout) || errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+				continue
+			}
+			return call.version, call.err
+		}
+		call := &versionCall{done: make(chan struct{})}
+		s.version.pending = call
+		s.version.mu.Unlock()
+
+		call.version, call.err = s.lookupVersion(ctx)
+
+		s.version.mu.Lock()
+		if call.err == nil {
+			s.version.version = call.version
+		}
+		s.version.pending = nil
+		s.version.mu.Unlock()
+		close(call.done)
+		return call.version, call.err
+	}
+}
+
+// lookupVersion runs solc --version
+func (s solc) lookupVersion(ctx context.Context) (string, error) {
+	if err := s.Prepare(ctx); err != nil {
+		return "", err
+	}
+
+	var stdout bytes.Buffer
+	_, err := s.runner.Run(ctx, shared.RunSpec{
+		Package:  "eth",
+		Func:     "Version",
+		Tool:     "solc",
+		Image:    s.image,
+		Platform: s.platform,
+		Cmd:      []string{"--version"},
+		Stdout:   &stdout,
+	})
+	if err != nil {
+		return "", err
+	}
+	m := versionPattern.FindStringSubmatch(stdout.String())
+	if m == nil {
+		return "", fmt.Errorf("unable to parse solc version: %s", strings.TrimSpace(stdout.String()))
+	}
+	return m[1], nil
+}
+
+// validateOptions checks opts against the version of solc in use, which
+// is only looked up when an option depends on it
+func (s solc) validateOptions(ctx context.Context, opts CompileOptions) error {
+	var version string
+	if opts.versioned() {
+		v, err := s.Version(ctx)
+		if err != nil {
+			return err
+		}
+		version = v
+	}
+	return opts.Validate(version)
+}
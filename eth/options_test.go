@@ -0,0 +1,190 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func solcVersionOutput(version string) narwhaltest.Script {
+	return narwhaltest.Script{Stdout: "solc, the solidity compiler commandline interface\nVersion: " + version + "+commit.7893614a.Linux.g++\n"}
+}
+
+func TestCompileOptionsValidate(t *testing.T) {
+	testcases := []struct {
+		options CompileOptions
+		version string
+		wantErr error
+	}{
+		{
+			options: CompileOptions{Optimize: true, OptimizeRuns: 1000, ViaIR: true, MetadataHash: MetadataHashNone, NoCBORMetadata: true, RevertStrings: RevertStringsStrip},
+			version: "0.8.28",
+			wantErr: nil,
+		},
+		{
+			options: CompileOptions{OptimizeRuns: 1000},
+			wantErr: ErrInvalidCompileOptions,
+		},
+		{
+			options: CompileOptions{MetadataHash: "swarm"},
+			wantErr: ErrInvalidCompileOptions,
+		},
+		{
+			options: CompileOptions{MetadataHash: MetadataHashIPFS, NoCBORMetadata: true},
+			wantErr: ErrInvalidCompileOptions,
+		},
+		{
+			options: CompileOptions{RevertStrings: "verbose"},
+			wantErr: ErrInvalidCompileOptions,
+		},
+		{
+			options: CompileOptions{ViaIR: true},
+			version: "0.8.12",
+			wantErr: ErrInvalidCompileOptions,
+		},
+		{
+			options: CompileOptions{NoCBORMetadata: true},
+			version: "0.8.17",
+			wantErr: ErrInvalidCompileOptions,
+		},
+		{
+			options: CompileOptions{Optimize: true, OptimizeRuns: 200},
+			version: "0.4.26",
+			wantErr: nil,
+		},
+	}
+	for i, tc := range testcases {
+		err := tc.options.Validate(tc.version)
+		assert.True(t, errors.Is(err, tc.wantErr), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.wantErr, err))
+	}
+}
+
+func TestCompileProjectOptions(t *testing.T) {
+	engine := narwhaltest.NewEngine(solcVersionOutput("0.8.28"))
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	project := Project{
+		Root:       "/work/token",
+		Sources:    []string{"src/Token.sol"},
+		Options:    CompileOptions{Optimize: true, OptimizeRuns: 10000, ViaIR: true, MetadataHash: MetadataHashNone},
+		EVMVersion: EVMVerCancun,
+	}
+	for i := 0; i < 2; i++ {
+		_, err = s.CompileProject(context.Background(), "solc", project, "/out")
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+	}
+
+	creates := engine.Creates()
+	if assert.Len(t, creates, 3) {
+		assert.Equal(t, []string{"--version"}, []string(creates[0].Config.Cmd))
+		want := []string{
+			"--abi", "--bin", "src/Token.sol",
+			"--base-path", "/opt/project",
+			"--allow-paths", "/opt/project",
+			"--optimize", "--optimize-runs", "10000", "--via-ir", "--metadata-hash", "none",
+			"-o", "/opt/abi", "--evm-version", "cancun",
+		}
+		assert.Equal(t, want, []string(creates[1].Config.Cmd))
+	}
+}
+
+func TestCompileOptionsVersion(t *testing.T) {
+	engine := narwhaltest.NewEngine(solcVersionOutput("0.8.12"))
+	s, err := NewSolcWithEngine(engine, "0.8.12")
+	assert.NoError(t, err)
+
+	_, err = s.CompileProject(context.Background(), "solc", Project{
+		Root:       "/work/token",
+		Sources:    []string{"src/Token.sol"},
+		Options:    CompileOptions{ViaIR: true},
+		EVMVersion: EVMVerLondon,
+	}, "/out")
+	assert.True(t, errors.Is(err, ErrInvalidCompileOptions))
+	assert.Len(t, engine.Creates(), 1)
+
+	version, err := s.Version(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "0.8.12", version)
+}
+
+func TestSolcVersionConcurrent(t *testing.T) {
+	// The first lookup hangs until its caller gives up
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Hang: true}, solcVersionOutput("0.8.28"))
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := s.Version(firstCtx)
+		first <- err
+	}()
+	for len(engine.Creates()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A caller waiting behind the lookup in flight is bounded by its own ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = s.Version(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), fmt.Sprintf("Got: %v", err))
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// A failed lookup is not cached
+	cancelFirst()
+	assert.True(t, errors.Is(<-first, context.Canceled))
+	version, err := s.Version(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "0.8.28", version)
+	assert.Len(t, engine.Creates(), 2)
+}
+
+func TestCompileStandardJSONOptions(t *testing.T) {
+	engine := narwhaltest.NewEngine(solcVersionOutput("0.8.28"), narwhaltest.Script{Stdout: `{}`})
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	_, err = s.CompileStandardJSON(context.Background(), StandardJSONInput{
+		Sources: map[string]StandardJSONSource{"Token.sol": {Content: "contract Token {}"}},
+		Settings: StandardJSONSettings{
+			Options: CompileOptions{Optimize: true, OptimizeRuns: 500, ViaIR: true, NoCBORMetadata: true, RevertStrings: RevertStringsDebug},
+		},
+	})
+	assert.NoError(t, err)
+
+	var sent struct {
+		Settings map[string]json.RawMessage `json:"settings"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(engine.Stdin("fake-2")), &sent))
+	assert.JSONEq(t, `{"enabled": true, "runs": 500}`, string(sent.Settings["optimizer"]))
+	assert.JSONEq(t, `true`, string(sent.Settings["viaIR"]))
+	assert.JSONEq(t, `{"appendCBOR": false}`, string(sent.Settings["metadata"]))
+	assert.JSONEq(t, `{"revertStrings": "debug"}`, string(sent.Settings["debug"]))
+	assert.NotContains(t, sent.Settings, "Options")
+}
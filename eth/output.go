@@ -0,0 +1,349 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/paulwizviz/narwhal/shared"
+)
+
+var (
+	// ErrInvalidOutput represents an output solc does not produce
+	ErrInvalidOutput = errors.New("invalid solc output selection")
+)
+
+// Output represents an artefact solc can produce for a contract
+type Output string
+
+// Outputs selectable in CompileProject and CompileProjectOutputs
+const (
+	OutputABI           Output = "abi"
+	OutputBin           Output = "bin"
+	OutputBinRuntime    Output = "bin-runtime"
+	OutputStorageLayout Output = "storage-layout"
+	// OutputHashes are the method identifiers of the contract's functions
+	OutputHashes  Output = "hashes"
+	OutputUserDoc Output = "userdoc"
+	OutputDevDoc  Output = "devdoc"
+	// OutputAST is the compact JSON AST of each source file
+	OutputAST      Output = "ast-compact-json"
+	OutputGas      Output = "gas"
+	OutputMetadata Output = "metadata"
+)
+
+// standardJSONOutputs maps outputs to their Standard JSON selection
+var standardJSONOutputs = map[Output]string{
+	OutputABI:           "abi",
+	OutputBin:           "evm.bytecode",
+	OutputBinRuntime:    "evm.deployedBytecode",
+	OutputStorageLayout: "storageLayout",
+	OutputHashes:        "evm.methodIdentifiers",
+	OutputUserDoc:       "userdoc",
+	OutputDevDoc:        "devdoc",
+	OutputAST:           "ast",
+	OutputGas:           "evm.gasEstimates",
+	OutputMetadata:      "metadata",
+}
+
+// flag returns the solc command line flag writing the output to the
+// output path. Gas estimates are only printed by solc, so they are
+// available from CompileProjectOutputs alone.
+func (o Output) flag() (string, error) {
+	if _, ok := standardJSONOutputs[o]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidOutput, o)
+	}
+	if o == OutputGas {
+		return "", fmt.Errorf("%w: %s is not written to the output path, use CompileProjectOutputs", ErrInvalidOutput, o)
+	}
+	return "--" + string(o), nil
+}
+
+// OutputSelection returns the Standard JSON output selection of outputs
+// for every contract
+func OutputSelection(outputs ...Output) (map[string]map[string][]string, error) {
+	contract := []string{}
+	file := []string{}
+	for _, o := range outputs {
+		sel, ok := standardJSONOutputs[o]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidOutput, o)
+		}
+		if o == OutputAST {
+			file = append(file, sel)
+			continue
+		}
+		contract = append(contract, sel)
+	}
+	selection := map[string][]string{"*": contract}
+	if len(file) > 0 {
+		selection[""] = file
+	}
+	return map[string]map[string][]string{"*": selection}, nil
+}
+
+// StorageLayout represents the layout of a contract's state variables
+type StorageLayout struct {
+	Storage []StorageSlot          `json:"storage"`
+	Types   map[string]StorageType `json:"types"`
+}
+
+// StorageSlot represents the location of a state variable or struct member
+type StorageSlot struct {
+	ASTID    int    `json:"astId"`
+	Contract string `json:"contract"`
+	Label    string `json:"label"`
+	Offset   int    `json:"offset"`
+	Slot     string `json:"slot"`
+	// Type is a key of StorageLayout.Types
+	Type string `json:"type"`
+}
+
+// StorageType represents a type found in a StorageLayout
+type StorageType struct {
+	Encoding      string `json:"encoding"`
+	Label         string `json:"label"`
+	NumberOfBytes string `json:"numberOfBytes"`
+	// Base is the element type of arrays
+	Base string `json:"base,omitempty"`
+	// Key and Value are the types of mappings
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+	// Members are the members of structs
+	Members []StorageSlot `json:"members,omitempty"`
+}
+
+// UserDoc represents the NatSpec user documentation of a contract
+type UserDoc struct {
+	Kind    string                    `json:"kind"`
+	Version int                       `json:"version"`
+	Notice  string                    `json:"notice,omitempty"`
+	Methods map[string]UserDocEntry   `json:"methods,omitempty"`
+	Events  map[string]UserDocEntry   `json:"events,omitempty"`
+	Errors  map[string][]UserDocEntry `json:"errors,omitempty"`
+}
+
+// UserDocEntry represents the user documentation of a member
+type UserDocEntry struct {
+	Notice string `json:"notice,omitempty"`
+}
+
+// DevDoc represents the NatSpec developer documentation of a contract
+type DevDoc struct {
+	Kind           string                   `json:"kind"`
+	Version        int                      `json:"version"`
+	Title          string                   `json:"title,omitempty"`
+	Author         string                   `json:"author,omitempty"`
+	Details        string                   `json:"details,omitempty"`
+	Methods        map[string]DevDocEntry   `json:"methods,omitempty"`
+	Events         map[string]DevDocEntry   `json:"events,omitempty"`
+	Errors         map[string][]DevDocEntry `json:"errors,omitempty"`
+	StateVariables map[string]DevDocEntry   `json:"stateVariables,omitempty"`
+}
+
+// DevDocEntry represents the developer documentation of a member
+type DevDocEntry struct {
+	Details string            `json:"details,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+	Returns map[string]string `json:"returns,omitempty"`
+}
+
+// GasEstimates represents the gas estimates of a contract. Estimates are
+// decimal strings, or "infinite" when unbounded.
+type GasEstimates struct {
+	Creation CreationGas `json:"creation"`
+	// External maps function signatures to their estimates
+	External map[string]string `json:"external,omitempty"`
+	// Internal maps internal functions to their estimates
+	Internal map[string]string `json:"internal,omitempty"`
+}
+
+// CreationGas represents the gas estimates of deploying a contract
+type CreationGas struct {
+	CodeDepositCost string `json:"codeDepositCost"`
+	ExecutionCost   string `json:"executionCost"`
+	TotalCost       string `json:"totalCost"`
+}
+
+// ASTNode represents a node of a compact JSON AST. Only the fields common
+// to all nodes are decoded; StandardJSONSourceOutput.AST holds the rest.
+type ASTNode struct {
+	ID       int       `json:"id"`
+	NodeType string    `json:"nodeType"`
+	Src      string    `json:"src"`
+	Name     string    `json:"name,omitempty"`
+	Nodes    []ASTNode `json:"nodes,omitempty"`
+}
+
+// ParseAST decodes the source's AST, if it was selected
+func (s StandardJSONSourceOutput) ParseAST() (*ASTNode, error) {
+	if len(s.AST) == 0 {
+		return nil, nil
+	}
+	var node ASTNode
+	if err := json.Unmarshal(s.AST, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// Metadata represents the metadata solc records for a contract, e.g. for
+// source verification
+type Metadata struct {
+	Compiler MetadataCompiler `json:"compiler"`
+	Language string           `json:"language"`
+	Output   MetadataOutput   `json:"output"`
+	Settings MetadataSettings `json:"settings"`
+	// Sources maps file names to the sources the contract was compiled from
+	Sources map[string]MetadataSource `json:"sources"`
+	Version int                       `json:"version"`
+}
+
+// MetadataCompiler represents the compiler that produced a contract
+type MetadataCompiler struct {
+	// Version is the full version, e.g. 0.8.28+commit.7893614a
+	Version   string `json:"version"`
+	Keccak256 string `json:"keccak256,omitempty"`
+}
+
+// MetadataOutput represents the interface and documentation of a contract
+type MetadataOutput struct {
+	ABI     []ABIEntry `json:"abi"`
+	UserDoc UserDoc    `json:"userdoc"`
+	DevDoc  DevDoc     `json:"devdoc"`
+}
+
+// MetadataSettings represents the settings a contract was compiled with
+type MetadataSettings struct {
+	// CompilationTarget maps the contract's file name to its name
+	CompilationTarget map[string]string     `json:"compilationTarget"`
+	EVMVersion        string                `json:"evmVersion"`
+	Libraries         map[string]string     `json:"libraries,omitempty"`
+	Metadata          StandardJSONMetadata  `json:"metadata"`
+	Optimizer         StandardJSONOptimizer `json:"optimizer"`
+	Remappings        []string              `json:"remappings"`
+	ViaIR             bool                  `json:"viaIR,omitempty"`
+}
+
+// MetadataSource represents a source file a contract was compiled from
+type MetadataSource struct {
+	Keccak256 string   `json:"keccak256"`
+	License   string   `json:"license,omitempty"`
+	URLs      []string `json:"urls,omitempty"`
+	// Content is set in place of URLs when the metadata embeds sources
+	Content string `json:"content,omitempty"`
+}
+
+// ParseMetadata decodes the contract's metadata, if it was selected. The
+// raw Metadata string is kept as is, since its hash is embedded in the
+// bytecode.
+func (c StandardJSONContract) ParseMetadata() (*Metadata, error) {
+	if c.Metadata == "" {
+		return nil, nil
+	}
+	var m Metadata
+	if err := json.Unmarshal([]byte(c.Metadata), &m); err != nil {
+		return nil, fmt.Errorf("invalid contract metadata: %w", err)
+	}
+	return &m, nil
+}
+
+func (s solc) CompileProjectOutputs(ctx context.Context, containerName string, project Project) (*StandardJSONOutput, error) {
+	if err := s.Prepare(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validateOptions(ctx, project.Options); err != nil {
+		return nil, err
+	}
+	return compileProjectOutputs(ctx, s.runner, s.image, containerName, s.platform, project)
+}
+
+func compileProjectOutputs(ctx context.Context, runner *shared.Runner, image string, name string, platform shared.DockerPlatformConfig, project Project) (*StandardJSONOutput, error) {
+
+	layout, err := project.layout()
+	if err != nil {
+		return nil, err
+	}
+	outputs := project.Outputs
+	if len(outputs) == 0 {
+		outputs = []Output{OutputABI, OutputBin}
+	}
+	selection, err := OutputSelection(outputs...)
+	if err != nil {
+		return nil, err
+	}
+
+	input := StandardJSONInput{
+		Sources: map[string]StandardJSONSource{},
+		Settings: StandardJSONSettings{
+			EVMVersion:      project.EVMVersion,
+			Remappings:      project.Remappings,
+			OutputSelection: selection,
+		},
+	}
+	// Sources are read by solc from the mounted project
+	for _, src := range layout.sources {
+		input.Sources[src] = StandardJSONSource{URLs: []string{src}}
+	}
+	project.Options.applyTo(&input.Settings)
+
+	return runStandardJSON(ctx, runner, shared.RunSpec{
+		Package:  "eth",
+		Func:     "compileProjectOutputs",
+		Tool:     "solc",
+		Name:     name,
+		Image:    image,
+		Platform: platform,
+		Cmd:      append([]string{"--standard-json"}, layout.pathArgs...),
+		Mounts:   layout.mounts,
+		WorkDir:  localProjectFolder,
+	}, input)
+}
+
+// runStandardJSON runs solc as per spec with input fed over stdin and
+// parses its output
+func runStandardJSON(ctx context.Context, runner *shared.Runner, spec shared.RunSpec, input StandardJSONInput) (*StandardJSONOutput, error) {
+	if input.Language == "" {
+		input.Language = "Solidity"
+	}
+	in, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout bytes.Buffer
+	spec.Stdin = bytes.NewReader(in)
+	spec.Stdout = &stdout
+	if _, err := runner.Run(ctx, spec); err != nil {
+		return nil, err
+	}
+
+	var output StandardJSONOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("invalid solc output: %w", err)
+	}
+	if err := output.compilationErr(); err != nil {
+		return &output, err
+	}
+	return &output, nil
+}
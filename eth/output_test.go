@@ -0,0 +1,225 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/stretchr/testify/assert"
+)
+
+const projectOutputs = `{
+	"contracts": {
+		"contracts/Vault.sol": {
+			"Vault": {
+				"storageLayout": {
+					"storage": [
+						{"astId": 3, "contract": "contracts/Vault.sol:Vault", "label": "owner", "offset": 0, "slot": "0", "type": "t_address"},
+						{"astId": 7, "contract": "contracts/Vault.sol:Vault", "label": "balances", "offset": 0, "slot": "1", "type": "t_mapping(t_address,t_uint256)"}
+					],
+					"types": {
+						"t_address": {"encoding": "inplace", "label": "address", "numberOfBytes": "20"},
+						"t_mapping(t_address,t_uint256)": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => uint256)", "numberOfBytes": "32", "value": "t_uint256"},
+						"t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"}
+					}
+				},
+				"userdoc": {"kind": "user", "methods": {"deposit()": {"notice": "Deposits the value sent"}}, "version": 1},
+				"devdoc": {"kind": "dev", "methods": {"withdraw(uint256)": {"params": {"amount": "wei to withdraw"}}}, "title": "A vault", "version": 1},
+				"evm": {
+					"methodIdentifiers": {"deposit()": "d0e30db0", "withdraw(uint256)": "2e1a7d4d"},
+					"gasEstimates": {
+						"creation": {"codeDepositCost": "187200", "executionCost": "infinite", "totalCost": "infinite"},
+						"external": {"deposit()": "24487", "withdraw(uint256)": "infinite"}
+					}
+				}
+			}
+		}
+	},
+	"sources": {
+		"contracts/Vault.sol": {"id": 0, "ast": {"id": 12, "nodeType": "SourceUnit", "src": "0:310:0", "nodes": [{"id": 11, "nodeType": "ContractDefinition", "name": "Vault", "src": "25:285:0"}]}}
+	}
+}`
+
+// metadataOutput holds metadata as emitted by solc 0.8.28 for a contract
+// compiled with the optimizer and a remapping
+const metadataOutput = `{
+ "contracts": {
+  "contracts/Vault.sol": {
+   "Vault": {
+    "metadata": "{\"compiler\":{\"version\":\"0.8.28+commit.7893614a\"},\"language\":\"Solidity\",\"output\":{\"abi\":[{\"inputs\":[],\"name\":\"deposit\",\"outputs\":[],\"stateMutability\":\"payable\",\"type\":\"function\"}],\"devdoc\":{\"kind\":\"dev\",\"methods\":{},\"title\":\"A vault\",\"version\":1},\"userdoc\":{\"kind\":\"user\",\"methods\":{\"deposit()\":{\"notice\":\"Deposits the value sent\"}},\"version\":1}},\"settings\":{\"compilationTarget\":{\"contracts/Vault.sol\":\"Vault\"},\"evmVersion\":\"cancun\",\"libraries\":{},\"metadata\":{\"bytecodeHash\":\"ipfs\"},\"optimizer\":{\"enabled\":true,\"runs\":200},\"remappings\":[\":@openzeppelin/=lib/openzeppelin-contracts/\"]},\"sources\":{\"contracts/Vault.sol\":{\"keccak256\":\"0x5c1ed0b8b1fb0b0fcbd1f8c3a3ffaa8ea1d3c5ba1b7c8f2e0e68d9b1a1c2d3e4\",\"license\":\"MIT\",\"urls\":[\"bzz-raw://8f3c7d4e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c\",\"dweb:/ipfs/QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG\"]}},\"version\":1}"
+   }
+  }
+ }
+}`
+
+func TestParseMetadata(t *testing.T) {
+	var out StandardJSONOutput
+	assert.NoError(t, json.Unmarshal([]byte(metadataOutput), &out))
+
+	vault := out.Contracts["contracts/Vault.sol"]["Vault"]
+	m, err := vault.ParseMetadata()
+	assert.NoError(t, err)
+	if !assert.NotNil(t, m) {
+		return
+	}
+	assert.Equal(t, "0.8.28+commit.7893614a", m.Compiler.Version)
+	assert.Equal(t, "Solidity", m.Language)
+	assert.Equal(t, 1, m.Version)
+	assert.Equal(t, "Vault", m.Settings.CompilationTarget["contracts/Vault.sol"])
+	assert.Equal(t, EVMVerCancun, m.Settings.EVMVersion)
+	assert.Equal(t, StandardJSONOptimizer{Enabled: true, Runs: 200}, m.Settings.Optimizer)
+	assert.Equal(t, "ipfs", m.Settings.Metadata.BytecodeHash)
+	assert.Equal(t, []string{":@openzeppelin/=lib/openzeppelin-contracts/"}, m.Settings.Remappings)
+	if assert.Len(t, m.Output.ABI, 1) {
+		assert.Equal(t, "deposit", m.Output.ABI[0].Name)
+	}
+	assert.Equal(t, "Deposits the value sent", m.Output.UserDoc.Methods["deposit()"].Notice)
+	assert.Equal(t, "A vault", m.Output.DevDoc.Title)
+	source := m.Sources["contracts/Vault.sol"]
+	assert.Equal(t, "MIT", source.License)
+	assert.Len(t, source.URLs, 2)
+
+	// Contracts compiled without the metadata output have none
+	m, err = StandardJSONContract{}.ParseMetadata()
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+}
+
+func TestCompileProjectOutputsFlags(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	_, err = s.CompileProject(context.Background(), "solc", Project{
+		Root:       "/work/vault",
+		Sources:    []string{"contracts/Vault.sol"},
+		EVMVersion: EVMVerParis,
+		Outputs:    []Output{OutputBinRuntime, OutputStorageLayout, OutputHashes, OutputAST},
+	}, "/out")
+	assert.NoError(t, err)
+
+	want := []string{
+		"--bin-runtime", "--storage-layout", "--hashes", "--ast-compact-json",
+		"contracts/Vault.sol",
+		"--base-path", "/opt/project",
+		"--allow-paths", "/opt/project",
+		"-o", "/opt/abi", "--evm-version", "paris",
+	}
+	creates := engine.Creates()
+	if assert.Len(t, creates, 1) {
+		assert.Equal(t, want, []string(creates[0].Config.Cmd))
+	}
+}
+
+func TestCompileProjectOutputs(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Stdout: projectOutputs})
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	out, err := s.CompileProjectOutputs(context.Background(), "solc", Project{
+		Root:       "/work/vault",
+		Sources:    []string{"contracts/Vault.sol"},
+		EVMVersion: EVMVerParis,
+		Outputs:    []Output{OutputStorageLayout, OutputHashes, OutputUserDoc, OutputDevDoc, OutputGas, OutputAST},
+	})
+	assert.NoError(t, err)
+
+	creates := engine.Creates()
+	if assert.Len(t, creates, 1) {
+		assert.Equal(t, []string{"--standard-json", "--base-path", "/opt/project", "--allow-paths", "/opt/project"}, []string(creates[0].Config.Cmd))
+		assert.Equal(t, "/opt/project", creates[0].Config.WorkingDir)
+		assert.Len(t, creates[0].HostConfig.Mounts, 1)
+	}
+
+	var sent StandardJSONInput
+	assert.NoError(t, json.Unmarshal([]byte(engine.Stdin("fake-1")), &sent))
+	assert.Equal(t, []string{"contracts/Vault.sol"}, sent.Sources["contracts/Vault.sol"].URLs)
+	assert.Equal(t, []string{"storageLayout", "evm.methodIdentifiers", "userdoc", "devdoc", "evm.gasEstimates"}, sent.Settings.OutputSelection["*"]["*"])
+	assert.Equal(t, []string{"ast"}, sent.Settings.OutputSelection["*"][""])
+
+	if !assert.NotNil(t, out) {
+		return
+	}
+	vault := out.Contracts["contracts/Vault.sol"]["Vault"]
+	if assert.NotNil(t, vault.StorageLayout) && assert.Len(t, vault.StorageLayout.Storage, 2) {
+		balances := vault.StorageLayout.Storage[1]
+		assert.Equal(t, "1", balances.Slot)
+		assert.Equal(t, "t_address", vault.StorageLayout.Types[balances.Type].Key)
+	}
+	assert.Equal(t, "d0e30db0", vault.EVM.MethodIdentifiers["deposit()"])
+	if assert.NotNil(t, vault.EVM.GasEstimates) {
+		assert.Equal(t, "187200", vault.EVM.GasEstimates.Creation.CodeDepositCost)
+		assert.Equal(t, "infinite", vault.EVM.GasEstimates.External["withdraw(uint256)"])
+	}
+	if assert.NotNil(t, vault.UserDoc) {
+		assert.Equal(t, "Deposits the value sent", vault.UserDoc.Methods["deposit()"].Notice)
+	}
+	if assert.NotNil(t, vault.DevDoc) {
+		assert.Equal(t, "A vault", vault.DevDoc.Title)
+		assert.Equal(t, "wei to withdraw", vault.DevDoc.Methods["withdraw(uint256)"].Params["amount"])
+	}
+	ast, err := out.Sources["contracts/Vault.sol"].ParseAST()
+	assert.NoError(t, err)
+	if assert.NotNil(t, ast) && assert.Len(t, ast.Nodes, 1) {
+		assert.Equal(t, "SourceUnit", ast.NodeType)
+		assert.Equal(t, "Vault", ast.Nodes[0].Name)
+	}
+}
+
+func TestOutputInvalid(t *testing.T) {
+	testcases := []struct {
+		outputs []Output
+	}{
+		{outputs: []Output{"asm"}},
+		{outputs: []Output{OutputABI, "--abi"}},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		s, err := NewSolcWithEngine(engine, "0.8.28")
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		project := Project{Root: "/work/vault", Sources: []string{"Vault.sol"}, EVMVersion: EVMVerParis, Outputs: tc.outputs}
+		_, err = s.CompileProject(context.Background(), "solc", project, "/out")
+		assert.True(t, errors.Is(err, ErrInvalidOutput), fmt.Sprintf("Case: %d Got: %v", i, err))
+		_, err = s.CompileProjectOutputs(context.Background(), "solc", project)
+		assert.True(t, errors.Is(err, ErrInvalidOutput), fmt.Sprintf("Case: %d Got: %v", i, err))
+		assert.Len(t, engine.Creates(), 0, fmt.Sprintf("Case: %d", i))
+	}
+}
+
+func TestCompileProjectGas(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	// solc prints gas estimates rather than writing them to the output path
+	_, err = s.CompileProject(context.Background(), "solc", Project{
+		Root:       "/work/vault",
+		Sources:    []string{"Vault.sol"},
+		EVMVersion: EVMVerParis,
+		Outputs:    []Output{OutputABI, OutputGas},
+	}, "/out")
+	assert.True(t, errors.Is(err, ErrInvalidOutput))
+	assert.Contains(t, err.Error(), "CompileProjectOutputs")
+	assert.Len(t, engine.Creates(), 0)
+}
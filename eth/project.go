@@ -0,0 +1,212 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/paulwizviz/narwhal/shared"
+)
+
+const (
+	localProjectFolder = "/opt/project"
+	localIncludeFolder = "/opt/include"
+)
+
+var (
+	// ErrInvalidProject represents a Project that cannot be compiled
+	ErrInvalidProject = errors.New("invalid solidity project")
+)
+
+// Project represents a tree of Solidity sources compiled together, so that
+// their imports resolve
+type Project struct {
+	// Root is the project directory on the host. It is the compiler's base
+	// path and imports are resolved relative to it.
+	Root string
+	// Sources are the entry files to compile, relative to Root
+	Sources []string
+	// IncludePaths are library directories, e.g. node_modules or lib, in
+	// which imports not found under Root are looked up. Relative paths are
+	// relative to Root.
+	IncludePaths []string
+	// AllowPaths are further directories sources may be read from, e.g.
+	// for imports by relative path outside Root. Relative paths are
+	// relative to Root.
+	AllowPaths []string
+	// Remappings rewrite import paths, e.g. as loaded with LoadRemappings
+	// or LoadFoundryRemappings
+	Remappings []Remapping
+	// Options set the optimizer, via-IR, metadata and revert string
+	// settings
+	Options    CompileOptions
+	EVMVersion string
+	// Outputs select the artefacts to produce. They default to the ABI and
+	// bytecode.
+	Outputs []Output
+	// Overwrite replaces artefacts already in the output path
+	Overwrite bool
+}
+
+func (s solc) CompileProject(ctx context.Context, containerName string, project Project, outPath string) (string, error) {
+	if err := s.Prepare(ctx); err != nil {
+		return "", err
+	}
+	if err := s.validateOptions(ctx, project.Options); err != nil {
+		return "", err
+	}
+	return compileProject(ctx, s.runner, s.image, containerName, s.platform, project, outPath)
+}
+
+func compileProject(ctx context.Context, runner *shared.Runner, image string, name string, platform shared.DockerPlatformConfig, project Project, outPath string) (string, error) {
+
+	layout, err := project.layout()
+	if err != nil {
+		return "", err
+	}
+
+	localABIFolder := "/opt/abi"
+	mounts := append(layout.mounts, shared.Mount{
+		Source: outPath,
+		Target: localABIFolder,
+	})
+
+	outputs := project.Outputs
+	if len(outputs) == 0 {
+		outputs = []Output{OutputABI, OutputBin}
+	}
+	var cmd []string
+	for _, o := range outputs {
+		flag, err := o.flag()
+		if err != nil {
+			return "", err
+		}
+		cmd = append(cmd, flag)
+	}
+	for _, r := range project.Remappings {
+		cmd = append(cmd, r.String())
+	}
+	cmd = append(cmd, layout.sources...)
+	cmd = append(cmd, layout.pathArgs...)
+	cmd = append(cmd, project.Options.args()...)
+	cmd = append(cmd, "-o", localABIFolder, "--evm-version", project.EVMVersion)
+	if project.Overwrite {
+		cmd = append(cmd, "--overwrite")
+	}
+
+	result, err := runner.Run(ctx, shared.RunSpec{
+		Package:  "eth",
+		Func:     "compileProject",
+		Tool:     "solc",
+		Name:     name,
+		Image:    image,
+		Platform: platform,
+		Cmd:      cmd,
+		Mounts:   mounts,
+		WorkDir:  localProjectFolder,
+	})
+	return result.ContainerID, err
+}
+
+// projectLayout represents a Project as seen from the container
+type projectLayout struct {
+	mounts []shared.Mount
+	// sources are the entry files relative to the base path
+	sources []string
+	// pathArgs are the base, include and allow path arguments of solc
+	pathArgs []string
+}
+
+func (p Project) layout() (projectLayout, error) {
+	var layout projectLayout
+
+	if !isEVMVerCorrect(p.EVMVersion) {
+		return layout, ErrInvalidEVMVersion
+	}
+	if p.Root == "" || len(p.Sources) == 0 {
+		return layout, fmt.Errorf("%w: root and sources are required", ErrInvalidProject)
+	}
+	root, err := filepath.Abs(p.Root)
+	if err != nil {
+		return layout, fmt.Errorf("%w: %v", ErrInvalidProject, err)
+	}
+
+	layout.mounts = []shared.Mount{
+		{
+			Source:   root,
+			Target:   localProjectFolder,
+			ReadOnly: true,
+		},
+	}
+	// paths maps host directories to the container, mounting those outside
+	// the project root
+	paths := func(dirs []string) []string {
+		var targets []string
+		for _, dir := range dirs {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(root, dir)
+			}
+			if rel, ok := within(root, dir); ok {
+				targets = append(targets, path.Join(localProjectFolder, rel))
+				continue
+			}
+			target := fmt.Sprintf("%s/%d", localIncludeFolder, len(layout.mounts)-1)
+			layout.mounts = append(layout.mounts, shared.Mount{
+				Source:   dir,
+				Target:   target,
+				ReadOnly: true,
+			})
+			targets = append(targets, target)
+		}
+		return targets
+	}
+	includePaths := paths(p.IncludePaths)
+	allowPaths := append([]string{localProjectFolder}, includePaths...)
+	allowPaths = append(allowPaths, paths(p.AllowPaths)...)
+
+	for _, src := range p.Sources {
+		rel, ok := within(root, filepath.Join(root, src))
+		if filepath.IsAbs(src) || !ok {
+			return layout, fmt.Errorf("%w: source %s is not within the project root", ErrInvalidProject, src)
+		}
+		layout.sources = append(layout.sources, rel)
+	}
+
+	layout.pathArgs = []string{"--base-path", localProjectFolder}
+	for _, p := range includePaths {
+		layout.pathArgs = append(layout.pathArgs, "--include-path", p)
+	}
+	layout.pathArgs = append(layout.pathArgs, "--allow-paths", strings.Join(allowPaths, ","))
+	return layout, nil
+}
+
+// within returns the slash separated path of target relative to root, if
+// target is root or below it
+func within(root string, target string) (string, bool) {
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
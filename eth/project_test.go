@@ -0,0 +1,105 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileProject(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	_, err = s.CompileProject(context.Background(), "solc", Project{
+		Root:         "/work/token",
+		Sources:      []string{"contracts/Token.sol", "contracts/Vault.sol"},
+		IncludePaths: []string{"node_modules", "/home/dev/lib"},
+		AllowPaths:   []string{"../shared"},
+		EVMVersion:   EVMVerParis,
+	}, "/out")
+	assert.NoError(t, err)
+
+	want := []string{
+		"--abi", "--bin", "contracts/Token.sol", "contracts/Vault.sol",
+		"--base-path", "/opt/project",
+		"--include-path", "/opt/project/node_modules",
+		"--include-path", "/opt/include/0",
+		"--allow-paths", "/opt/project,/opt/project/node_modules,/opt/include/0,/opt/include/1",
+		"-o", "/opt/abi", "--evm-version", "paris",
+	}
+	wantMounts := []shared.Mount{
+		{Source: "/work/token", Target: "/opt/project", ReadOnly: true},
+		{Source: "/home/dev/lib", Target: "/opt/include/0", ReadOnly: true},
+		{Source: "/work/shared", Target: "/opt/include/1", ReadOnly: true},
+		{Source: "/out", Target: "/opt/abi"},
+	}
+	creates := engine.Creates()
+	if assert.Len(t, creates, 1) {
+		assert.Equal(t, want, []string(creates[0].Config.Cmd))
+		assert.Equal(t, "/opt/project", creates[0].Config.WorkingDir)
+		mounts := creates[0].HostConfig.Mounts
+		if assert.Len(t, mounts, len(wantMounts)) {
+			for i, m := range wantMounts {
+				assert.Equal(t, m.Source, mounts[i].Source, fmt.Sprintf("Mount: %d", i))
+				assert.Equal(t, m.Target, mounts[i].Target, fmt.Sprintf("Mount: %d", i))
+				assert.Equal(t, m.ReadOnly, mounts[i].ReadOnly, fmt.Sprintf("Mount: %d", i))
+			}
+		}
+	}
+}
+
+func TestCompileProjectInvalid(t *testing.T) {
+	testcases := []struct {
+		project Project
+		wantErr error
+	}{
+		{
+			project: Project{Root: "/work/token", EVMVersion: EVMVerParis},
+			wantErr: ErrInvalidProject,
+		},
+		{
+			project: Project{Root: "/work/token", Sources: []string{"../other/Token.sol"}, EVMVersion: EVMVerParis},
+			wantErr: ErrInvalidProject,
+		},
+		{
+			project: Project{Root: "/work/token", Sources: []string{"/work/token/Token.sol"}, EVMVersion: EVMVerParis},
+			wantErr: ErrInvalidProject,
+		},
+		{
+			project: Project{Root: "/work/token", Sources: []string{"Token.sol"}, EVMVersion: "Paris"},
+			wantErr: ErrInvalidEVMVersion,
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		s, err := NewSolcWithEngine(engine, "0.8.28")
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		_, err = s.CompileProject(context.Background(), "solc", tc.project, "/out")
+		assert.True(t, errors.Is(err, tc.wantErr), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.wantErr, err))
+		assert.Len(t, engine.Creates(), 0, fmt.Sprintf("Case: %d", i))
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrInvalidRemapping represents a malformed import remapping
+	ErrInvalidRemapping = errors.New("invalid remapping")
+)
+
+// Remapping represents an import remapping, [context:]prefix=target, e.g.
+// @openzeppelin/=lib/openzeppelin-contracts/. Targets are relative to the
+// project root.
+type Remapping struct {
+	// Context restricts the remapping to imports from files whose path
+	// starts with it. Empty applies it to all files.
+	Context string
+	Prefix  string
+	Target  string
+}
+
+// ParseRemapping parses a remapping of the form [context:]prefix=target
+func ParseRemapping(s string) (Remapping, error) {
+	lhs, target, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return Remapping{}, fmt.Errorf("%w: %q", ErrInvalidRemapping, s)
+	}
+	var r Remapping
+	if context, prefix, ok := strings.Cut(lhs, ":"); ok {
+		r.Context, r.Prefix = context, prefix
+	} else {
+		r.Prefix = lhs
+	}
+	r.Target = target
+	if r.Prefix == "" {
+		return Remapping{}, fmt.Errorf("%w: %q", ErrInvalidRemapping, s)
+	}
+	return r, nil
+}
+
+// String returns the remapping as passed to solc
+func (r Remapping) String() string {
+	if r.Context != "" {
+		return r.Context + ":" + r.Prefix + "=" + r.Target
+	}
+	return r.Prefix + "=" + r.Target
+}
+
+// MarshalText encodes the remapping as in Standard JSON settings
+func (r Remapping) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes a remapping as in Standard JSON settings
+func (r *Remapping) UnmarshalText(b []byte) error {
+	parsed, err := ParseRemapping(string(b))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// LoadRemappings reads a remappings.txt file, one remapping per line.
+// Blank lines and lines starting with # are ignored.
+func LoadRemappings(name string) ([]Remapping, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var remappings []Remapping
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := ParseRemapping(line)
+		if err != nil {
+			return nil, err
+		}
+		remappings = append(remappings, r)
+	}
+	return remappings, scanner.Err()
+}
+
+// LoadFoundryRemappings reads the remappings of a profile, default when
+// profile is empty, from a foundry.toml file. A profile without
+// remappings inherits those of the default profile.
+func LoadFoundryRemappings(name string, profile string) ([]Remapping, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	values, err := foundryRemappings(string(b), "profile."+profile)
+	if err == nil && values == nil && profile != "default" {
+		// Profiles inherit the settings of the default profile
+		values, err = foundryRemappings(string(b), "profile.default")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	var remappings []Remapping
+	for _, v := range values {
+		r, err := ParseRemapping(v)
+		if err != nil {
+			return nil, err
+		}
+		remappings = append(remappings, r)
+	}
+	return remappings, nil
+}
+
+// foundryRemappings extracts the strings of the remappings array in a TOML
+// table. It understands the subset of TOML foundry.toml files use for it:
+// a single or multi-line array of basic or literal strings.
+func foundryRemappings(doc string, table string) ([]string, error) {
+	var (
+		inTable bool
+		inArray bool
+		values  []string
+	)
+	for _, line := range strings.Split(doc, "\n") {
+		line = stripComment(line)
+		trimmed := strings.TrimSpace(line)
+		if !inArray && strings.HasPrefix(trimmed, "[") {
+			name := strings.Trim(trimmed, "[] ")
+			inTable = name == table
+			continue
+		}
+		if !inTable {
+			continue
+		}
+		if !inArray {
+			key, value, ok := strings.Cut(trimmed, "=")
+			if !ok || strings.TrimSpace(key) != "remappings" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if !strings.HasPrefix(value, "[") {
+				return nil, errors.New("remappings is not an array")
+			}
+			inArray = true
+			trimmed = value[1:]
+		}
+		end := strings.Contains(trimmed, "]")
+		if end {
+			trimmed = trimmed[:strings.LastIndex(trimmed, "]")]
+		}
+		for _, item := range strings.Split(trimmed, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			if len(item) < 2 || (item[0] != '"' && item[0] != '\'') || item[len(item)-1] != item[0] {
+				return nil, fmt.Errorf("invalid remappings entry %s", item)
+			}
+			values = append(values, item[1:len(item)-1])
+		}
+		if end {
+			return values, nil
+		}
+	}
+	if inArray {
+		return nil, errors.New("unterminated remappings array")
+	}
+	return values, nil
+}
+
+// stripComment removes a # comment outside of strings from a TOML line
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRemapping(t *testing.T) {
+	testcases := []struct {
+		input   string
+		want    Remapping
+		wantErr error
+	}{
+		{
+			input: "@openzeppelin/=lib/openzeppelin-contracts/",
+			want:  Remapping{Prefix: "@openzeppelin/", Target: "lib/openzeppelin-contracts/"},
+		},
+		{
+			input: "src/legacy:@openzeppelin/=lib/openzeppelin-contracts-v4/",
+			want:  Remapping{Context: "src/legacy", Prefix: "@openzeppelin/", Target: "lib/openzeppelin-contracts-v4/"},
+		},
+		{
+			input:   "@openzeppelin/",
+			wantErr: ErrInvalidRemapping,
+		},
+		{
+			input:   "=lib/forge-std/src/",
+			wantErr: ErrInvalidRemapping,
+		},
+	}
+	for i, tc := range testcases {
+		got, err := ParseRemapping(tc.input)
+		assert.True(t, errors.Is(err, tc.wantErr), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.wantErr, err))
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+		if err == nil {
+			assert.Equal(t, tc.input, got.String(), fmt.Sprintf("Case: %d", i))
+		}
+	}
+}
+
+func TestLoadRemappings(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "remappings.txt")
+	assert.NoError(t, os.WriteFile(name, []byte("# libraries\n@openzeppelin/=lib/openzeppelin-contracts/\n\nforge-std/=lib/forge-std/src/\n"), 0644))
+
+	got, err := LoadRemappings(name)
+	assert.NoError(t, err)
+	assert.Equal(t, []Remapping{
+		{Prefix: "@openzeppelin/", Target: "lib/openzeppelin-contracts/"},
+		{Prefix: "forge-std/", Target: "lib/forge-std/src/"},
+	}, got)
+}
+
+const foundryToml = `[profile.default]
+src = "src"
+out = "out"
+libs = ["lib"]
+remappings = [
+    "@openzeppelin/=lib/openzeppelin-contracts/", # OpenZeppelin
+    'forge-std/=lib/forge-std/src/',
+]
+
+[profile.ci]
+fuzz = { runs = 10000 }
+
+[profile.legacy]
+remappings = ["@openzeppelin/=lib/openzeppelin-contracts-v4/"]
+`
+
+func TestLoadFoundryRemappings(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "foundry.toml")
+	assert.NoError(t, os.WriteFile(name, []byte(foundryToml), 0644))
+
+	testcases := []struct {
+		profile string
+		want    []Remapping
+	}{
+		{
+			profile: "",
+			want: []Remapping{
+				{Prefix: "@openzeppelin/", Target: "lib/openzeppelin-contracts/"},
+				{Prefix: "forge-std/", Target: "lib/forge-std/src/"},
+			},
+		},
+		{
+			profile: "ci",
+			want: []Remapping{
+				{Prefix: "@openzeppelin/", Target: "lib/openzeppelin-contracts/"},
+				{Prefix: "forge-std/", Target: "lib/forge-std/src/"},
+			},
+		},
+		{
+			profile: "legacy",
+			want:    []Remapping{{Prefix: "@openzeppelin/", Target: "lib/openzeppelin-contracts-v4/"}},
+		},
+	}
+	for i, tc := range testcases {
+		got, err := LoadFoundryRemappings(name, tc.profile)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
+
+func TestRemappingsApplied(t *testing.T) {
+	remappings := []Remapping{{Prefix: "@openzeppelin/", Target: "lib/openzeppelin-contracts/"}}
+
+	engine := narwhaltest.NewEngine(narwhaltest.Script{}, narwhaltest.Script{Stdout: `{}`})
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	_, err = s.CompileProject(context.Background(), "solc", Project{
+		Root:       "/work/token",
+		Sources:    []string{"src/Token.sol"},
+		Remappings: remappings,
+		EVMVersion: EVMVerParis,
+	}, "/out")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--abi", "--bin", "@openzeppelin/=lib/openzeppelin-contracts/", "src/Token.sol"}, []string(engine.Creates()[0].Config.Cmd[:4]))
+
+	_, err = s.CompileStandardJSON(context.Background(), StandardJSONInput{
+		Sources:  map[string]StandardJSONSource{"src/Token.sol": {Content: "contract Token {}"}},
+		Settings: StandardJSONSettings{Remappings: remappings},
+	})
+	assert.NoError(t, err)
+	var sent map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(engine.Stdin("fake-2")), &sent))
+	assert.Equal(t, []any{"@openzeppelin/=lib/openzeppelin-contracts/"}, sent["settings"].(map[string]any)["remappings"])
+}
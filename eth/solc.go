@@ -22,15 +22,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"os"
 	"path/filepath"
 
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/mount"
-	dockersdk "github.com/docker/docker/client"
-	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/paulwizviz/narwhal/shared"
 )
 
@@ -70,8 +63,13 @@ var (
 // Solc represents docker clients that wrap solidity compiler
 type Solc interface {
 
+	// Prepare makes the tool image available locally as per the pull
+	// policy. Tool calls prepare the image on first use, so it only needs
+	// calling to pull ahead of time, e.g. at start up under a deadline.
+	Prepare(ctx context.Context) error
 	// CompileSol is a function trigger a container to compile solidity. It will return
-	// an error if any compiled artefacts already exist in the outPath
+	// an error if any compiled artefacts already exist in the outPath. If solc exits
+	// with a non-zero status, a *shared.ToolError with Op shared.OpRunTool is returned with the container ID
 	//
 	// Arguments:
 	//
@@ -83,86 +81,97 @@ type Solc interface {
 	CompileSol(ctx context.Context, containerName string, solPath string, solFile string, outPath string, evmVer string) (string, error)
 	// CompileSolWithOverride is compile solidity and override any compiled artefacts in outPath
 	CompileSolWithOverride(ctx context.Context, containerName string, solPath string, solFile string, outPath string, evmVer string) (string, error)
-	// RemoveContainer remove container for a given ID
+	// Version returns the version of solc in the image, e.g. 0.8.28. It
+	// is looked up with solc --version on first use.
+	Version(ctx context.Context) (string, error)
+	// CompileProject compiles the entry files of a project tree, mounting its
+	// root and library directories so that imports resolve, and writes the
+	// artefacts to outPath. If solc exits with a non-zero status, a
+	// *shared.ToolError with Op shared.OpRunTool is returned with the
+	// container ID.
+	CompileProject(ctx context.Context, containerName string, project Project, outPath string) (string, error)
+	// CompileProjectOutputs compiles a project tree like CompileProject but
+	// returns the artefacts selected by project.Outputs parsed into Go
+	// types, rather than writing them to a directory. If solc reports
+	// errors, the output is returned with an error matching ErrCompilation.
+	CompileProjectOutputs(ctx context.Context, containerName string, project Project) (*StandardJSONOutput, error)
+	// CompileStandardJSON compiles input with solc --standard-json, fed over
+	// stdin, and returns the parsed output without touching the filesystem.
+	// If solc reports errors, the output is returned with an error matching
+	// ErrCompilation.
+	CompileStandardJSON(ctx context.Context, input StandardJSONInput) (*StandardJSONOutput, error)
+	// RemoveContainer remove a stopped container for a given ID. Containers are
+	// removed after each run unless the client is configured WithKeepContainer.
 	RemoveContainer(ctx context.Context, containerID string) error
 	// RemoveContainerForce remove container for ID with no exception
 	RemoveContainerForce(ctx context.Context, containerID string) error
 }
 
 type solc struct {
-	cli          *dockersdk.Client
-	osPlatform   string
-	archPlatform string
-	image        string
+	cli       shared.Engine
+	runner    *shared.Runner
+	toolImage *shared.ToolImage
+	version   *solcVersion
+	platform  shared.DockerPlatformConfig
+	image     string
+}
+
+func (s solc) Prepare(ctx context.Context) error {
+	if err := s.toolImage.Prepare(ctx); err != nil {
+		return shared.PullImageError(err, "eth", "Prepare")
+	}
+	return nil
 }
 
 func (s solc) CompileSol(ctx context.Context, name string, solPath string, solFile string, outPath string, evmVer string) (string, error) {
-	return compileSol(ctx, s.cli, s.image, name, s.osPlatform, s.archPlatform, solPath, solFile, outPath, evmVer, false)
+	if err := s.Prepare(ctx); err != nil {
+		return "", err
+	}
+	return compileSol(ctx, s.runner, s.image, name, s.platform, solPath, solFile, outPath, evmVer, false)
 }
 
 func (s solc) CompileSolWithOverride(ctx context.Context, name string, solPath string, solFile string, outPath string, evmVer string) (string, error) {
-	return compileSol(ctx, s.cli, s.image, name, s.osPlatform, s.archPlatform, solPath, solFile, outPath, evmVer, true)
+	if err := s.Prepare(ctx); err != nil {
+		return "", err
+	}
+	return compileSol(ctx, s.runner, s.image, name, s.platform, solPath, solFile, outPath, evmVer, true)
 }
 
-func compileSol(ctx context.Context, client *dockersdk.Client, image string, name string, platformOS string, arch string, solPath string, solFile string, outPath string, evmVer string, override bool) (string, error) {
+func compileSol(ctx context.Context, runner *shared.Runner, image string, name string, platform shared.DockerPlatformConfig, solPath string, solFile string, outPath string, evmVer string, override bool) (string, error) {
 
 	if !isEVMVerCorrect(evmVer) {
 		return "", ErrInvalidEVMVersion
 	}
 
-	platform := &v1.Platform{
-		OS:           platformOS,
-		Architecture: arch,
-	}
-
 	localSolFolder := "/opt/solidity"
 	localABIFolder := "/opt/abi"
 
-	var cmd []string
+	cmd := []string{"--abi", "--bin", fmt.Sprintf("%s/%s", localSolFolder, solFile), "-o", localABIFolder, "--evm-version", evmVer}
 	if override {
-		cmd = []string{"--abi", "--bin", fmt.Sprintf("%s/%s", localSolFolder, solFile), "-o", localABIFolder, "--evm-version", evmVer, "--overwrite"}
-	} else {
-		cmd = []string{"--abi", "--bin", fmt.Sprintf("%s/%s", localSolFolder, solFile), "-o", localABIFolder, "--evm-version", evmVer}
-	}
-
-	containConfig := &container.Config{
-		Image: image,
-		Cmd:   cmd,
+		cmd = append(cmd, "--overwrite")
 	}
 
-	hostConfig := &container.HostConfig{
-		Mounts: []mount.Mount{
+	result, err := runner.Run(ctx, shared.RunSpec{
+		Package:  "eth",
+		Func:     "compileSol",
+		Tool:     "solc",
+		Name:     name,
+		Image:    image,
+		Platform: platform,
+		Cmd:      cmd,
+		Mounts: []shared.Mount{
 			{
-				Type:   mount.TypeBind,
-				Source: filepath.Join(solPath, solFile),
-				Target: fmt.Sprintf("%s/%s", localSolFolder, solFile),
+				Source:   filepath.Join(solPath, solFile),
+				Target:   fmt.Sprintf("%s/%s", localSolFolder, solFile),
+				ReadOnly: true,
 			},
 			{
-				Type:   mount.TypeBind,
 				Source: outPath,
 				Target: localABIFolder,
 			},
 		},
-	}
-
-	resp, err := client.ContainerCreate(ctx, containConfig, hostConfig, nil, platform, name)
-	if err != nil {
-		return "", shared.CreateContainerErr(err, "eth", "compileSol")
-	}
-
-	if err := client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", shared.StartContainerErr(err, "eth", "compileSol")
-	}
-
-	out, err := client.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Timestamps: true})
-	if err != nil {
-		return "", shared.ContainerLogErr(err, "eth", "compileSol")
-	}
-	defer out.Close()
-
-	io.Copy(os.Stdout, out)
-
-	return resp.ID, nil
+	})
+	return result.ContainerID, err
 }
 
 func isEVMVerCorrect(version string) bool {
@@ -191,31 +200,57 @@ func (s solc) RemoveContainerForce(ctx context.Context, containerID string) erro
 	return shared.RemoveContainerForce(ctx, s.cli, containerID)
 }
 
+// NewSolc instantiate an ethereum/solc client. Unless configured otherwise
+// by opts, it uses ethereum/solc:stable for Linux/amd64 platform, a Docker
+// client configured from the environment and pulls the image only if it is
+// not present. The image is prepared on first use; see Solc.Prepare.
+func NewSolc(opts ...shared.Option) (Solc, error) {
+	cfg := shared.NewConfig(append([]shared.Option{
+		shared.WithImage(fmt.Sprintf("%s:%s", EthereumSolcImage, "stable")),
+		shared.WithPlatform(shared.PlatformLinuxAMD64()),
+	}, opts...)...)
+
+	if cfg.Engine == nil {
+		cli, err := shared.NewDockerEngine()
+		if err != nil {
+			return nil, shared.InstantiateClientErr(err, "eth", "NewSolc")
+		}
+		cfg.Engine = cli
+	}
+
+	return &solc{
+		cli:       cfg.Engine,
+		runner:    shared.NewRunner(cfg.Engine, opts...),
+		toolImage: shared.NewToolImage(cfg),
+		version:   &solcVersion{},
+		platform:  cfg.Platform,
+		image:     cfg.Image,
+	}, nil
+}
+
 // NewDefaultSolc instantiate an ethereum/solc client for Linux/amd64 platform
 //
 // Arguments:
 //
-// - imgTag is the tag associated with ethereum/solc
-func NewDefaultSolc(imageTag string) (Solc, error) {
-	cli, err := dockersdk.NewClientWithOpts(dockersdk.FromEnv, dockersdk.WithAPIVersionNegotiation())
-	if err != nil {
-		return nil, shared.InstantiateClientErr(err, "eth", "NewDefaultSolc")
-	}
-	solcImage := fmt.Sprintf("%s:%s", EthereumSolcImage, imageTag)
+// - imgTag is the tag associated with ethereum/solc, or a digest sha256:...
+// - opts configure the client further as per NewSolc
+func NewDefaultSolc(imageTag string, opts ...shared.Option) (Solc, error) {
+	return NewSolc(append([]shared.Option{
+		shared.WithImage(shared.ImageRef(EthereumSolcImage, imageTag)),
+	}, opts...)...)
+}
 
-	p := shared.PlatformLinuxAMD64()
-	reader, err := cli.ImagePull(context.Background(), solcImage, image.PullOptions{
-		Platform: fmt.Sprintf("%s/%s", p.OS, p.Arch),
-	})
-	if err != nil {
-		return nil, shared.PullImageError(err, "eth", "NewDefaultSolc")
-	}
-	defer reader.Close()
-	io.Copy(os.Stdout, reader)
-	return &solc{
-		cli:          cli,
-		osPlatform:   p.OS,
-		archPlatform: p.Arch,
-		image:        solcImage,
-	}, nil
+// NewSolcWithEngine instantiate an ethereum/solc client for Linux/amd64 platform
+// backed by the given container engine
+//
+// Arguments:
+//
+// - engine is a Docker client or a fake engine
+// - imgTag is the tag associated with ethereum/solc, or a digest sha256:...
+// - opts configure the client further as per NewSolc
+func NewSolcWithEngine(engine shared.Engine, imageTag string, opts ...shared.Option) (Solc, error) {
+	return NewSolc(append([]shared.Option{
+		shared.WithClient(engine),
+		shared.WithImage(shared.ImageRef(EthereumSolcImage, imageTag)),
+	}, opts...)...)
 }
@@ -19,9 +19,13 @@
 package eth
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -84,3 +88,111 @@ func TestIsEVMVerCorrect(t *testing.T) {
 		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
 	}
 }
+
+func TestCompileSol(t *testing.T) {
+	testcases := []struct {
+		override bool
+		want     []string
+	}{
+		{
+			override: false,
+			want:     []string{"--abi", "--bin", "/opt/solidity/hello.sol", "-o", "/opt/abi", "--evm-version", "paris"},
+		},
+		{
+			override: true,
+			want:     []string{"--abi", "--bin", "/opt/solidity/hello.sol", "-o", "/opt/abi", "--evm-version", "paris", "--overwrite"},
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		s, err := NewSolcWithEngine(engine, "0.8.28")
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+
+		if tc.override {
+			_, err = s.CompileSolWithOverride(context.Background(), "solc", "/src", "hello.sol", "/out", EVMVerParis)
+		} else {
+			_, err = s.CompileSol(context.Background(), "solc", "/src", "hello.sol", "/out", EVMVerParis)
+		}
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+
+		creates := engine.Creates()
+		if assert.Len(t, creates, 1, fmt.Sprintf("Case: %d", i)) {
+			assert.Equal(t, tc.want, []string(creates[0].Config.Cmd), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, creates[0].Config.Cmd))
+			assert.Equal(t, "ethereum/solc:0.8.28", creates[0].Config.Image, fmt.Sprintf("Case: %d", i))
+			assert.Equal(t, "/src/hello.sol", creates[0].HostConfig.Mounts[0].Source, fmt.Sprintf("Case: %d", i))
+		}
+	}
+}
+
+func TestCompileSolToolFailure(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Stderr: "Error: Expected ';'", ExitCode: 1})
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	id, err := s.CompileSol(context.Background(), "solc", "/src", "hello.sol", "/out", EVMVerParis)
+	assert.True(t, errors.Is(err, shared.ErrToolFailed))
+	assert.Equal(t, "fake-1", id)
+}
+
+func TestGenGoBinding(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	a, err := NewABIGenWithEngine(engine, "alltools-stable")
+	assert.NoError(t, err)
+
+	_, err = a.GenGoBinding(context.Background(), "abigen", "/abi", "/out", "hello", "HelloWorld")
+	assert.NoError(t, err)
+
+	want := []string{"abigen", "--abi", "/opt/abi/HelloWorld.abi", "--bin", "/opt/abi/HelloWorld.bin", "--pkg", "hello", "--type", "HelloWorld", "--out", "/opt/binding/hello/HelloWorld.go"}
+	creates := engine.Creates()
+	if assert.Len(t, creates, 1) {
+		assert.Equal(t, want, []string(creates[0].Config.Cmd))
+		assert.Equal(t, "ethereum/client-go:alltools-stable", creates[0].Config.Image)
+	}
+	pulls := engine.Pulls()
+	if assert.Len(t, pulls, 1) {
+		assert.Equal(t, "linux/amd64", pulls[0].Options.Platform)
+	}
+}
+
+func TestNewSolcOptions(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	s, err := NewSolc(
+		shared.WithClient(engine),
+		shared.WithImage("registry.local/solc:0.8.28"),
+		shared.WithPlatform(shared.PlatformLinuxARM64()),
+	)
+	assert.NoError(t, err)
+
+	_, err = s.CompileSol(context.Background(), "solc", "/src", "hello.sol", "/out", EVMVerParis)
+	assert.NoError(t, err)
+
+	pulls := engine.Pulls()
+	if assert.Len(t, pulls, 1) {
+		assert.Equal(t, "registry.local/solc:0.8.28", pulls[0].Image)
+		assert.Equal(t, "linux/arm64", pulls[0].Options.Platform)
+	}
+	creates := engine.Creates()
+	if assert.Len(t, creates, 1) {
+		assert.Equal(t, "registry.local/solc:0.8.28", creates[0].Config.Image)
+		assert.Equal(t, "arm64", creates[0].Platform.Architecture)
+	}
+}
+
+func TestSolcPrepare(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+	assert.Len(t, engine.Pulls(), 0)
+
+	assert.NoError(t, s.Prepare(context.Background()))
+	_, err = s.CompileSol(context.Background(), "solc", "/src", "hello.sol", "/out", EVMVerParis)
+	assert.NoError(t, err)
+	assert.Len(t, engine.Pulls(), 1)
+
+	engine = narwhaltest.NewEngine()
+	s, err = NewSolcWithEngine(engine, "0.8.28", shared.WithPullPolicy(shared.PullNever))
+	assert.NoError(t, err)
+	_, err = s.CompileSol(context.Background(), "solc", "/src", "hello.sol", "/out", EVMVerParis)
+	assert.True(t, errors.Is(err, shared.ErrImageNotFound))
+	assert.Len(t, engine.Creates(), 0)
+}
@@ -0,0 +1,236 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/paulwizviz/narwhal/shared"
+)
+
+var (
+	// ErrCompilation represents a Standard JSON compilation that reported
+	// errors
+	ErrCompilation = errors.New("solidity compilation failed")
+)
+
+// StandardJSONInput represents the input of solc --standard-json
+type StandardJSONInput struct {
+	// Language defaults to Solidity
+	Language string                        `json:"language"`
+	Sources  map[string]StandardJSONSource `json:"sources"`
+	Settings StandardJSONSettings          `json:"settings"`
+}
+
+// StandardJSONSource represents a source unit, given by its Content
+type StandardJSONSource struct {
+	Content   string   `json:"content,omitempty"`
+	Keccak256 string   `json:"keccak256,omitempty"`
+	URLs      []string `json:"urls,omitempty"`
+}
+
+// StandardJSONSettings represents the settings of a Standard JSON
+// compilation
+type StandardJSONSettings struct {
+	EVMVersion string                 `json:"evmVersion,omitempty"`
+	Remappings []Remapping            `json:"remappings,omitempty"`
+	Optimizer  *StandardJSONOptimizer `json:"optimizer,omitempty"`
+	ViaIR      bool                   `json:"viaIR,omitempty"`
+	Metadata   *StandardJSONMetadata  `json:"metadata,omitempty"`
+	Debug      *StandardJSONDebug     `json:"debug,omitempty"`
+	// Options set the optimizer, viaIR, metadata and debug settings above.
+	// Unlike those, they are validated against the solc version in use.
+	Options CompileOptions `json:"-"`
+	// OutputSelection maps file names to contract names to outputs. It
+	// defaults to the ABI, metadata, bytecode and deployed bytecode of
+	// every contract.
+	OutputSelection map[string]map[string][]string `json:"outputSelection,omitempty"`
+}
+
+// StandardJSONOptimizer represents the optimizer settings
+type StandardJSONOptimizer struct {
+	Enabled bool `json:"enabled"`
+	Runs    int  `json:"runs,omitempty"`
+}
+
+// StandardJSONMetadata represents the metadata settings
+type StandardJSONMetadata struct {
+	BytecodeHash string `json:"bytecodeHash,omitempty"`
+	AppendCBOR   *bool  `json:"appendCBOR,omitempty"`
+}
+
+// StandardJSONDebug represents the debug settings
+type StandardJSONDebug struct {
+	RevertStrings string `json:"revertStrings,omitempty"`
+}
+
+// StandardJSONOutput represents the output of solc --standard-json
+type StandardJSONOutput struct {
+	Errors []StandardJSONError `json:"errors,omitempty"`
+	// Sources maps file names to their source unit ID
+	Sources map[string]StandardJSONSourceOutput `json:"sources,omitempty"`
+	// Contracts maps file names to contract names to their outputs
+	Contracts map[string]map[string]StandardJSONContract `json:"contracts,omitempty"`
+}
+
+// StandardJSONSourceOutput represents the output for a source unit
+type StandardJSONSourceOutput struct {
+	ID int `json:"id"`
+	// AST is the compact JSON AST, when selected. See ParseAST.
+	AST json.RawMessage `json:"ast,omitempty"`
+}
+
+// StandardJSONError represents an error or warning reported by solc
+type StandardJSONError struct {
+	SourceLocation   *SourceLocation `json:"sourceLocation,omitempty"`
+	Type             string          `json:"type"`
+	Component        string          `json:"component"`
+	Severity         string          `json:"severity"`
+	ErrorCode        string          `json:"errorCode,omitempty"`
+	Message          string          `json:"message"`
+	FormattedMessage string          `json:"formattedMessage,omitempty"`
+}
+
+// SourceLocation represents a range of a source file
+type SourceLocation struct {
+	File  string `json:"file"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// StandardJSONContract represents the outputs of a contract
+type StandardJSONContract struct {
+	ABI []ABIEntry `json:"abi,omitempty"`
+	// Metadata is the contract's metadata as a JSON string. See
+	// ParseMetadata.
+	Metadata      string          `json:"metadata,omitempty"`
+	StorageLayout *StorageLayout  `json:"storageLayout,omitempty"`
+	UserDoc       *UserDoc        `json:"userdoc,omitempty"`
+	DevDoc        *DevDoc         `json:"devdoc,omitempty"`
+	EVM           StandardJSONEVM `json:"evm"`
+}
+
+// StandardJSONEVM represents the EVM outputs of a contract
+type StandardJSONEVM struct {
+	Bytecode         Bytecode `json:"bytecode"`
+	DeployedBytecode Bytecode `json:"deployedBytecode"`
+	// MethodIdentifiers maps function signatures to their selectors
+	MethodIdentifiers map[string]string `json:"methodIdentifiers,omitempty"`
+	GasEstimates      *GasEstimates     `json:"gasEstimates,omitempty"`
+}
+
+// Bytecode represents compiled EVM code
+type Bytecode struct {
+	// Object is the hex encoded code, without 0x prefix
+	Object    string `json:"object"`
+	Opcodes   string `json:"opcodes,omitempty"`
+	SourceMap string `json:"sourceMap,omitempty"`
+	// LinkReferences maps file names to library names to the locations of
+	// their placeholders
+	LinkReferences map[string]map[string][]LinkReference `json:"linkReferences,omitempty"`
+}
+
+// LinkReference represents the location of a library placeholder in
+// bytecode, in bytes
+type LinkReference struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// ABIEntry represents a function, constructor, event, error, fallback or
+// receive of a contract ABI
+type ABIEntry struct {
+	Type            string     `json:"type"`
+	Name            string     `json:"name,omitempty"`
+	Inputs          []ABIParam `json:"inputs,omitempty"`
+	Outputs         []ABIParam `json:"outputs,omitempty"`
+	StateMutability string     `json:"stateMutability,omitempty"`
+	Anonymous       bool       `json:"anonymous,omitempty"`
+}
+
+// ABIParam represents a parameter of an ABIEntry
+type ABIParam struct {
+	Name         string     `json:"name"`
+	Type         string     `json:"type"`
+	InternalType string     `json:"internalType,omitempty"`
+	Indexed      bool       `json:"indexed,omitempty"`
+	Components   []ABIParam `json:"components,omitempty"`
+}
+
+// HasErrors reports whether solc reported any error, as opposed to
+// warnings
+func (o *StandardJSONOutput) HasErrors() bool {
+	for _, e := range o.Errors {
+		if e.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s solc) CompileStandardJSON(ctx context.Context, input StandardJSONInput) (*StandardJSONOutput, error) {
+	if err := s.Prepare(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.validateOptions(ctx, input.Settings.Options); err != nil {
+		return nil, err
+	}
+	return compileStandardJSON(ctx, s.runner, s.image, s.platform, input)
+}
+
+func compileStandardJSON(ctx context.Context, runner *shared.Runner, image string, platform shared.DockerPlatformConfig, input StandardJSONInput) (*StandardJSONOutput, error) {
+
+	if input.Settings.EVMVersion != "" && !isEVMVerCorrect(input.Settings.EVMVersion) {
+		return nil, ErrInvalidEVMVersion
+	}
+	input.Settings.Options.applyTo(&input.Settings)
+	if input.Settings.OutputSelection == nil {
+		input.Settings.OutputSelection = map[string]map[string][]string{
+			"*": {"*": {"abi", "metadata", "evm.bytecode", "evm.deployedBytecode"}},
+		}
+	}
+
+	return runStandardJSON(ctx, runner, shared.RunSpec{
+		Package:  "eth",
+		Func:     "compileStandardJSON",
+		Tool:     "solc",
+		Image:    image,
+		Platform: platform,
+		Cmd:      []string{"--standard-json"},
+	}, input)
+}
+
+// compilationErr returns an error matching ErrCompilation listing the
+// errors solc reported, if any
+func (o *StandardJSONOutput) compilationErr() error {
+	var msgs []string
+	for _, e := range o.Errors {
+		if e.Severity == "error" {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrCompilation, strings.Join(msgs, "; "))
+}
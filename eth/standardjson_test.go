@@ -0,0 +1,108 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/stretchr/testify/assert"
+)
+
+const standardJSONOutput = `{
+	"contracts": {
+		"hello.sol": {
+			"Hello": {
+				"abi": [
+					{"inputs": [], "name": "greet", "outputs": [{"internalType": "string", "name": "", "type": "string"}], "stateMutability": "pure", "type": "function"}
+				],
+				"metadata": "{\"compiler\":{\"version\":\"0.8.28+commit.7893614a\"}}",
+				"evm": {
+					"bytecode": {"object": "6080604052", "opcodes": "PUSH1 0x80 PUSH1 0x40 MSTORE", "sourceMap": "25:80:0:-:0", "linkReferences": {}},
+					"deployedBytecode": {"object": "60806040", "linkReferences": {}}
+				}
+			}
+		}
+	},
+	"errors": [
+		{"component": "general", "errorCode": "1878", "formattedMessage": "Warning: SPDX license identifier not provided", "message": "SPDX license identifier not provided", "severity": "warning", "sourceLocation": {"end": -1, "file": "hello.sol", "start": -1}, "type": "Warning"}
+	],
+	"sources": {"hello.sol": {"id": 0}}
+}`
+
+func TestCompileStandardJSON(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Stdout: standardJSONOutput})
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	input := StandardJSONInput{
+		Sources: map[string]StandardJSONSource{
+			"hello.sol": {Content: "contract Hello { function greet() public pure returns (string memory) { return \"hello\"; } }"},
+		},
+		Settings: StandardJSONSettings{EVMVersion: EVMVerParis},
+	}
+	out, err := s.CompileStandardJSON(context.Background(), input)
+	assert.NoError(t, err)
+
+	creates := engine.Creates()
+	if assert.Len(t, creates, 1) {
+		assert.Equal(t, []string{"--standard-json"}, []string(creates[0].Config.Cmd))
+		assert.True(t, creates[0].Config.OpenStdin)
+		assert.Empty(t, creates[0].HostConfig.Mounts)
+	}
+
+	var sent StandardJSONInput
+	assert.NoError(t, json.Unmarshal([]byte(engine.Stdin("fake-1")), &sent))
+	assert.Equal(t, "Solidity", sent.Language)
+	assert.Equal(t, input.Sources, sent.Sources)
+	assert.Equal(t, []string{"abi", "metadata", "evm.bytecode", "evm.deployedBytecode"}, sent.Settings.OutputSelection["*"]["*"])
+
+	if assert.NotNil(t, out) {
+		hello := out.Contracts["hello.sol"]["Hello"]
+		if assert.Len(t, hello.ABI, 1) {
+			assert.Equal(t, "greet", hello.ABI[0].Name)
+			assert.Equal(t, "string", hello.ABI[0].Outputs[0].Type)
+		}
+		assert.Equal(t, "6080604052", hello.EVM.Bytecode.Object)
+		assert.Equal(t, "60806040", hello.EVM.DeployedBytecode.Object)
+		assert.Contains(t, hello.Metadata, "0.8.28")
+		assert.False(t, out.HasErrors())
+		assert.Len(t, out.Errors, 1)
+	}
+}
+
+func TestCompileStandardJSONErrors(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Stdout: `{"errors": [{"component": "general", "message": "Expected ';' but got '}'", "severity": "error", "type": "ParserError"}], "sources": {}}`})
+	s, err := NewSolcWithEngine(engine, "0.8.28")
+	assert.NoError(t, err)
+
+	out, err := s.CompileStandardJSON(context.Background(), StandardJSONInput{
+		Sources: map[string]StandardJSONSource{"hello.sol": {Content: "contract Hello {"}},
+	})
+	assert.True(t, errors.Is(err, ErrCompilation))
+	if assert.NotNil(t, out) {
+		assert.Equal(t, "ParserError", out.Errors[0].Type)
+	}
+
+	_, err = s.CompileStandardJSON(context.Background(), StandardJSONInput{Settings: StandardJSONSettings{EVMVersion: "Cancun"}})
+	assert.True(t, errors.Is(err, ErrInvalidEVMVersion))
+}
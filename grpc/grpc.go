@@ -21,161 +21,104 @@ package grpc
 import (
 	"context"
 	"fmt"
-	"io"
-	"os"
 
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/mount"
-	dockersdk "github.com/docker/docker/client"
-	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/paulwizviz/narwhal/shared"
 )
 
+const (
+	// NarwhalProtocImage is the protoc image built by scripts/protoc.sh
+	NarwhalProtocImage = "narwhal/protoc:current"
+)
+
+// Protoc represents docker clients that wrap protoc compiler
 type Protoc interface {
-	// CompileProtosGo trigger protoc container to compile protofile
+	// Prepare makes the tool image available locally as per the pull
+	// policy. Tool calls prepare the image on first use, so it only needs
+	// calling to pull ahead of time, e.g. at start up under a deadline.
+	Prepare(ctx context.Context) error
+	// CompileProtosGo trigger protoc container to compile protofile. If protoc exits
+	// with a non-zero status, a *shared.ToolError with Op shared.OpRunTool is returned with the container ID
 	CompileProtosGo(ctx context.Context, containerName string, protoPath []string, outPath string, proto string) (string, error)
 	// CompileProtosGRPC trigger protoc container to compile protofile for grpc output
 	CompileProtosGRPC(ctx context.Context, containerName string, protoPath []string, outPath string, proto string) (string, error)
-	// RemoveContainer remove container for a given ID
+	// RemoveContainer remove a stopped container for a given ID. Containers are
+	// removed after each run unless the client is configured WithKeepContainer.
 	RemoveContainer(ctx context.Context, containerID string) error
 	// RemoveContainerForce remove container for ID with no exception
 	RemoveContainerForce(ctx context.Context, containerID string) error
 }
 
 type protoc struct {
-	cli          *dockersdk.Client
-	osPlatform   string
-	archPlatform string
-	image        string
+	cli       shared.Engine
+	runner    *shared.Runner
+	toolImage *shared.ToolImage
+	platform  shared.DockerPlatformConfig
+	image     string
 }
 
-func (p protoc) CompileProtosGo(ctx context.Context, containerName string, protoPaths []string, outPath string, proto string) (string, error) {
-	return compileProtosGo(ctx, p.cli, p.image, containerName, p.osPlatform, p.archPlatform, protoPaths, outPath, proto)
+func (p protoc) Prepare(ctx context.Context) error {
+	if err := p.toolImage.Prepare(ctx); err != nil {
+		return shared.PullImageError(err, "grpc", "Prepare")
+	}
+	return nil
 }
 
-func compileProtosGo(ctx context.Context, client *dockersdk.Client, image string, name string, platformOS string, arch string, protoPaths []string, outPath string, protoFile string) (string, error) {
+const localOutput = "/opt/out"
 
-	platform := &v1.Platform{
-		OS:           platformOS,
-		Architecture: arch,
-	}
-
-	var localProtoPaths string
-	for _, pp := range protoPaths {
-		localProtoPaths = localProtoPaths + fmt.Sprintf("--proto_path=%s,", pp)
+func (p protoc) CompileProtosGo(ctx context.Context, containerName string, protoPaths []string, outPath string, proto string) (string, error) {
+	if err := p.Prepare(ctx); err != nil {
+		return "", err
 	}
-
-	localProtoPaths = localProtoPaths[:len(localProtoPaths)-1]
-	localOutput := fmt.Sprintf("/opt/out")
-
-	cmd := []string{"--proto_path=/usr/local/include", localProtoPaths,
+	cmd := append(protoPathArgs(protoPaths),
 		fmt.Sprintf("--go_out=%s", localOutput),
 		"--go_opt=paths=source_relative",
-		protoFile}
-
-	containConfig := &container.Config{
-		Image: image,
-		Cmd:   cmd,
-	}
-
-	hostConfig := &container.HostConfig{
-		Mounts: []mount.Mount{
-			{
-				Type:   mount.TypeBind,
-				Source: protoFile,
-				Target: protoFile,
-			},
-			{
-				Type:   mount.TypeBind,
-				Source: outPath,
-				Target: localOutput,
-			},
-		},
-	}
-
-	resp, err := client.ContainerCreate(ctx, containConfig, hostConfig, nil, platform, name)
-	if err != nil {
-		return "", shared.CreateContainerErr(err, "grpc", "compileProtosGo")
-	}
-
-	if err := client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", shared.StartContainerErr(err, "grpc", "compileProtosGo")
-	}
-
-	out, err := client.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Timestamps: true})
-	if err != nil {
-		return "", shared.ContainerLogErr(err, "grpc", "compileProtosGo")
-	}
-	defer out.Close()
-
-	io.Copy(os.Stdout, out)
-
-	return resp.ID, nil
+		proto)
+	return compileProtos(ctx, p.runner, p.image, containerName, p.platform, "compileProtosGo", cmd, outPath, proto)
 }
 
 func (p protoc) CompileProtosGRPC(ctx context.Context, containerName string, protoPaths []string, outPath string, proto string) (string, error) {
-	return compileProtosGRPC(ctx, p.cli, p.image, containerName, p.osPlatform, p.archPlatform, protoPaths, outPath, proto)
-}
-
-func compileProtosGRPC(ctx context.Context, client *dockersdk.Client, image string, name string, platformOS string, arch string, protoPaths []string, outPath string, protoFile string) (string, error) {
-
-	platform := &v1.Platform{
-		OS:           platformOS,
-		Architecture: arch,
-	}
-
-	var localProtoPaths string
-	for _, pp := range protoPaths {
-		localProtoPaths = localProtoPaths + fmt.Sprintf("--proto_path=%s,", pp)
+	if err := p.Prepare(ctx); err != nil {
+		return "", err
 	}
-
-	localProtoPaths = localProtoPaths[:len(localProtoPaths)-1]
-	localOutput := fmt.Sprintf("/opt/out")
-
-	cmd := []string{"--proto_path=/usr/local/include", localProtoPaths,
+	cmd := append(protoPathArgs(protoPaths),
 		fmt.Sprintf("--go_out=%s", localOutput),
 		"--go_opt=paths=source_relative",
 		fmt.Sprintf("--go-grpc_out=%s", localOutput),
 		"--go-grpc_opt=paths=source_relative",
-		protoFile}
-	containConfig := &container.Config{
-		Image: image,
-		Cmd:   cmd,
+		proto)
+	return compileProtos(ctx, p.runner, p.image, containerName, p.platform, "compileProtosGRPC", cmd, outPath, proto)
+}
+
+func protoPathArgs(protoPaths []string) []string {
+	args := []string{"--proto_path=/usr/local/include"}
+	for _, pp := range protoPaths {
+		args = append(args, fmt.Sprintf("--proto_path=%s", pp))
 	}
+	return args
+}
 
-	hostConfig := &container.HostConfig{
-		Mounts: []mount.Mount{
+func compileProtos(ctx context.Context, runner *shared.Runner, image string, name string, platform shared.DockerPlatformConfig, fname string, cmd []string, outPath string, protoFile string) (string, error) {
+	result, err := runner.Run(ctx, shared.RunSpec{
+		Package:  "grpc",
+		Func:     fname,
+		Tool:     "protoc",
+		Name:     name,
+		Image:    image,
+		Platform: platform,
+		Cmd:      cmd,
+		Mounts: []shared.Mount{
 			{
-				Type:   mount.TypeBind,
-				Source: protoFile,
-				Target: protoFile,
+				Source:   protoFile,
+				Target:   protoFile,
+				ReadOnly: true,
 			},
 			{
-				Type:   mount.TypeBind,
 				Source: outPath,
 				Target: localOutput,
 			},
 		},
-	}
-
-	resp, err := client.ContainerCreate(ctx, containConfig, hostConfig, nil, platform, name)
-	if err != nil {
-		return "", shared.CreateContainerErr(err, "grpc", "compileProtosGRPC")
-	}
-
-	if err := client.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
-		return "", shared.StartContainerErr(err, "grpc", "compileProtosGRPC")
-	}
-
-	out, err := client.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true, Timestamps: true})
-	if err != nil {
-		return "", shared.ContainerLogErr(err, "grpc", "compileProtosGRPC")
-	}
-	defer out.Close()
-
-	io.Copy(os.Stdout, out)
-
-	return resp.ID, nil
+	})
+	return result.ContainerID, err
 }
 
 func (p protoc) RemoveContainer(ctx context.Context, containerID string) error {
@@ -186,17 +129,52 @@ func (p protoc) RemoveContainerForce(ctx context.Context, containerID string) er
 	return shared.RemoveContainerForce(ctx, p.cli, containerID)
 }
 
-// NewProtocWithLocalImageLinuxAMD64 instantiate a user specified image base on Linux and AMD64 platform
-func NewProtocWithLocalImageLinuxAMD64(img string) (Protoc, error) {
-	cli, err := dockersdk.NewClientWithOpts(dockersdk.FromEnv, dockersdk.WithAPIVersionNegotiation())
-	if err != nil {
-		return nil, shared.InstantiateClientErr(err, "grpc", "NewProtocWithLocalImageLinuxAMD64")
+// NewProtoc instantiate a protoc client. Unless configured otherwise by opts, it
+// uses narwhal/protoc:current for Linux/amd64 platform and a Docker client
+// configured from the environment. The image is built locally by
+// scripts/protoc.sh and not published, so it is never pulled unless opts set
+// another pull policy; a pull would fetch whatever docker.io/narwhal/protoc
+// holds. The image is prepared on first use; see Protoc.Prepare.
+func NewProtoc(opts ...shared.Option) (Protoc, error) {
+	cfg := shared.NewConfig(append([]shared.Option{
+		shared.WithImage(NarwhalProtocImage),
+		shared.WithPlatform(shared.PlatformLinuxAMD64()),
+		shared.WithPullPolicy(shared.PullNever),
+	}, opts...)...)
+
+	if cfg.Engine == nil {
+		cli, err := shared.NewDockerEngine()
+		if err != nil {
+			return nil, shared.InstantiateClientErr(err, "grpc", "NewProtoc")
+		}
+		cfg.Engine = cli
 	}
-	p := shared.PlatformLinuxAMD64()
+
 	return &protoc{
-		cli:          cli,
-		osPlatform:   p.OS,
-		archPlatform: p.Arch,
-		image:        img,
+		cli:       cfg.Engine,
+		runner:    shared.NewRunner(cfg.Engine, opts...),
+		toolImage: shared.NewToolImage(cfg),
+		platform:  cfg.Platform,
+		image:     cfg.Image,
 	}, nil
 }
+
+// NewProtocWithLocalImageLinuxAMD64 instantiate a user specified image base on Linux and AMD64 platform.
+// The image is never pulled. The opts configure the client further as per NewProtoc.
+func NewProtocWithLocalImageLinuxAMD64(img string, opts ...shared.Option) (Protoc, error) {
+	return NewProtoc(append([]shared.Option{
+		shared.WithImage(img),
+		shared.WithPullPolicy(shared.PullNever),
+	}, opts...)...)
+}
+
+// NewProtocWithEngine instantiate a user specified image base on Linux and AMD64
+// platform backed by the given container engine. As with NewProtoc, the image is
+// never pulled unless opts set another pull policy. The opts configure the
+// client further as per NewProtoc.
+func NewProtocWithEngine(engine shared.Engine, img string, opts ...shared.Option) (Protoc, error) {
+	return NewProtoc(append([]shared.Option{
+		shared.WithClient(engine),
+		shared.WithImage(img),
+	}, opts...)...)
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompileProtos(t *testing.T) {
+	testcases := []struct {
+		compile func(p Protoc) (string, error)
+		want    []string
+	}{
+		{
+			compile: func(p Protoc) (string, error) {
+				return p.CompileProtosGo(context.Background(), "protoc", []string{"/protos", "/vendor"}, "/out", "/protos/person.proto")
+			},
+			want: []string{
+				"--proto_path=/usr/local/include",
+				"--proto_path=/protos",
+				"--proto_path=/vendor",
+				"--go_out=/opt/out",
+				"--go_opt=paths=source_relative",
+				"/protos/person.proto",
+			},
+		},
+		{
+			compile: func(p Protoc) (string, error) {
+				return p.CompileProtosGRPC(context.Background(), "protoc", []string{"/protos"}, "/out", "/protos/person.proto")
+			},
+			want: []string{
+				"--proto_path=/usr/local/include",
+				"--proto_path=/protos",
+				"--go_out=/opt/out",
+				"--go_opt=paths=source_relative",
+				"--go-grpc_out=/opt/out",
+				"--go-grpc_opt=paths=source_relative",
+				"/protos/person.proto",
+			},
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		engine.AddImage("narwhal/protoc:current", shared.PlatformLinuxAMD64())
+		p, err := NewProtocWithEngine(engine, "narwhal/protoc:current")
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		id, err := tc.compile(p)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, "fake-1", id, fmt.Sprintf("Case: %d", i))
+
+		creates := engine.Creates()
+		if assert.Len(t, creates, 1, fmt.Sprintf("Case: %d", i)) {
+			assert.Equal(t, tc.want, []string(creates[0].Config.Cmd), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, creates[0].Config.Cmd))
+			assert.Equal(t, "narwhal/protoc:current", creates[0].Config.Image, fmt.Sprintf("Case: %d", i))
+		}
+	}
+}
+
+func TestNewProtocNeverPulls(t *testing.T) {
+	testcases := []struct {
+		newProtoc func(engine shared.Engine) (Protoc, error)
+	}{
+		{
+			newProtoc: func(engine shared.Engine) (Protoc, error) {
+				return NewProtoc(shared.WithClient(engine))
+			},
+		},
+		{
+			newProtoc: func(engine shared.Engine) (Protoc, error) {
+				return NewProtocWithEngine(engine, NarwhalProtocImage)
+			},
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		p, err := tc.newProtoc(engine)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+
+		_, err = p.CompileProtosGo(context.Background(), "protoc", []string{"/protos"}, "/out", "/protos/person.proto")
+		assert.True(t, errors.Is(err, shared.ErrImageNotFound), fmt.Sprintf("Case: %d Got: %v", i, err))
+		assert.Empty(t, engine.Pulls(), fmt.Sprintf("Case: %d", i))
+		assert.Empty(t, engine.Creates(), fmt.Sprintf("Case: %d", i))
+	}
+
+	// Pulling is opted into explicitly
+	engine := narwhaltest.NewEngine()
+	p, err := NewProtocWithEngine(engine, NarwhalProtocImage, shared.WithPullPolicy(shared.PullIfNotPresent))
+	assert.NoError(t, err)
+	assert.NoError(t, p.Prepare(context.Background()))
+	assert.Len(t, engine.Pulls(), 1)
+}
@@ -67,9 +67,7 @@ func main() {
 
 	fmt.Println(containerID)
 
-	// STEP 5: Remove solidity compiler container
-	if err := solc.RemoveContainerForce(context.TODO(), containerID); err != nil {
-		log.Fatal(err)
-	}
+	// The container is removed once the tool has run. Use
+	// shared.WithKeepContainer to retain it for debugging.
 
 }
@@ -73,8 +73,6 @@ func main() {
 
 	fmt.Println(containerID)
 
-	// STEP 4: Remover ABI generator container
-	if err := tool.RemoveContainerForce(context.TODO(), containerID); err != nil {
-		log.Fatal(err)
-	}
+	// The container is removed once the tool has run. Use
+	// shared.WithKeepContainer to retain it for debugging.
 }
@@ -0,0 +1,29 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+// Package narwhaltest provides an in-memory container engine for testing
+// code that uses this module without a Docker daemon.
+//
+// # Features:
+//
+// * Fake engine satisfying shared.Engine
+//
+// * Scripted tool output and exit status
+//
+// * Recording of calls made to the engine
+package narwhaltest
@@ -0,0 +1,649 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package narwhaltest
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing/iotest"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/paulwizviz/narwhal/shared"
+)
+
+// Script represents the scripted outcome of a single container run. Scripts
+// are consumed in order, one per ContainerCreate.
+type Script struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int64
+	// Hang keeps the container running until it is killed or the
+	// context passed to ContainerWait is done
+	Hang bool
+	// Files maps paths in the container to the content CopyFromContainer
+	// returns for them, e.g. outputs written by the tool
+	Files map[string]string
+
+	CreateErr error
+	StartErr  error
+	LogsErr   error
+	WaitErr   error
+	// StreamErr breaks the log stream after the scripted output
+	StreamErr error
+}
+
+// CreateCall records the arguments passed to ContainerCreate
+type CreateCall struct {
+	Config     *container.Config
+	HostConfig *container.HostConfig
+	Platform   *v1.Platform
+	Name       string
+}
+
+// PullCall records the arguments passed to ImagePull
+type PullCall struct {
+	Image   string
+	Options image.PullOptions
+}
+
+// CopyCall records the arguments passed to CopyToContainer
+type CopyCall struct {
+	ContainerID string
+	DstPath     string
+	Content     []byte
+}
+
+type fakeContainer struct {
+	id      string
+	name    string
+	image   string
+	labels  map[string]string
+	script  Script
+	running bool
+	// state overrides the state reported for a container that is not
+	// running, e.g. dead
+	state  string
+	killed chan struct{}
+}
+
+// stdinConn is the connection returned by ContainerAttach. It records
+// what is written to it and has nothing to read.
+type stdinConn struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (c *stdinConn) Read(b []byte) (int, error) { return 0, io.EOF }
+
+func (c *stdinConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	return c.buf.Write(b)
+}
+
+func (c *stdinConn) CloseWrite() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *stdinConn) Close() error                       { return c.CloseWrite() }
+func (c *stdinConn) LocalAddr() net.Addr                { return nil }
+func (c *stdinConn) RemoteAddr() net.Addr               { return nil }
+func (c *stdinConn) SetDeadline(t time.Time) error      { return nil }
+func (c *stdinConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *stdinConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *stdinConn) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+// Engine is an in-memory implementation of shared.Engine
+type Engine struct {
+	// PullOutput is returned as the body of every ImagePull
+	PullOutput string
+	// PullErr is returned by every ImagePull when set
+	PullErr error
+	// CopyErr is returned by every CopyToContainer when set, without
+	// reading the content
+	CopyErr error
+	// SystemInfo is returned by Info
+	SystemInfo system.Info
+	// Version is returned by ServerVersion. Set its Components to e.g.
+	// Podman Engine to emulate Podman.
+	Version types.Version
+	// Host is returned by DaemonHost. It defaults to a local unix socket.
+	Host string
+
+	mu         sync.Mutex
+	scripts    []Script
+	containers map[string]*fakeContainer
+	images     map[string]shared.DockerPlatformConfig
+	digests    map[string]string
+	stdins     map[string]*stdinConn
+	nextID     int
+	calls      []string
+	creates    []CreateCall
+	pulls      []PullCall
+	copies     []CopyCall
+	removed    []string
+}
+
+var _ shared.Engine = (*Engine)(nil)
+
+// NewEngine instantiate a fake engine that plays back scripts in order.
+// Runs beyond the supplied scripts exit with status 0 and no output.
+func NewEngine(scripts ...Script) *Engine {
+	return &Engine{
+		Host:       "unix:///var/run/docker.sock",
+		scripts:    scripts,
+		containers: map[string]*fakeContainer{},
+		images:     map[string]shared.DockerPlatformConfig{},
+		digests:    map[string]string{},
+		stdins:     map[string]*stdinConn{},
+	}
+}
+
+// AddImage makes an image available locally for the given platform
+func (e *Engine) AddImage(ref string, platform shared.DockerPlatformConfig) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.images[ref] = platform
+}
+
+// AddScript appends scripts to be played back by subsequent runs
+func (e *Engine) AddScript(scripts ...Script) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scripts = append(e.scripts, scripts...)
+}
+
+// Calls returns the names of engine methods invoked so far, in order
+func (e *Engine) Calls() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.calls...)
+}
+
+// Creates returns the recorded ContainerCreate calls
+func (e *Engine) Creates() []CreateCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]CreateCall(nil), e.creates...)
+}
+
+// Pulls returns the recorded ImagePull calls
+func (e *Engine) Pulls() []PullCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]PullCall(nil), e.pulls...)
+}
+
+// Copies returns the recorded CopyToContainer calls
+func (e *Engine) Copies() []CopyCall {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]CopyCall(nil), e.copies...)
+}
+
+// Removed returns the IDs of removed containers
+func (e *Engine) Removed() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.removed...)
+}
+
+// Containers returns the IDs of containers that have not been removed
+func (e *Engine) Containers() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var ids []string
+	for i := 1; i <= e.nextID; i++ {
+		id := containerID(i)
+		if _, ok := e.containers[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (e *Engine) record(method string) {
+	e.calls = append(e.calls, method)
+}
+
+func (e *Engine) lookup(id string) (*fakeContainer, error) {
+	if c, ok := e.containers[id]; ok {
+		return c, nil
+	}
+	for _, c := range e.containers {
+		if c.name != "" && c.name == strings.TrimPrefix(id, "/") {
+			return c, nil
+		}
+	}
+	return nil, errdefs.NotFound(fmt.Errorf("no such container: %s", id))
+}
+
+func containerID(n int) string {
+	return fmt.Sprintf("fake-%d", n)
+}
+
+// ContainerCreate records the call and registers a container with the next script
+func (e *Engine) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ContainerCreate")
+	e.creates = append(e.creates, CreateCall{
+		Config:     config,
+		HostConfig: hostConfig,
+		Platform:   platform,
+		Name:       containerName,
+	})
+
+	var script Script
+	if len(e.scripts) > 0 {
+		script = e.scripts[0]
+		e.scripts = e.scripts[1:]
+	}
+	if script.CreateErr != nil {
+		return container.CreateResponse{}, script.CreateErr
+	}
+	if containerName != "" {
+		for _, c := range e.containers {
+			if c.name == containerName {
+				return container.CreateResponse{}, errdefs.Conflict(fmt.Errorf("container name %q is already in use by %s", containerName, c.id))
+			}
+		}
+	}
+
+	e.nextID++
+	c := &fakeContainer{
+		id:     containerID(e.nextID),
+		name:   containerName,
+		script: script,
+		killed: make(chan struct{}),
+	}
+	if config != nil {
+		c.image = config.Image
+		c.labels = config.Labels
+	}
+	e.containers[c.id] = c
+	return container.CreateResponse{ID: c.id}, nil
+}
+
+// ContainerStart returns the scripted start error, if any
+func (e *Engine) ContainerStart(ctx context.Context, containerID string, options container.StartOptions) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ContainerStart")
+	c, err := e.lookup(containerID)
+	if err != nil {
+		return err
+	}
+	if c.script.StartErr != nil {
+		return c.script.StartErr
+	}
+	c.running = true
+	return nil
+}
+
+// StartContainer marks a container as running, as if it were started
+// and had not yet exited
+func (e *Engine) StartContainer(containerID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c, err := e.lookup(containerID); err == nil {
+		c.running = true
+	}
+}
+
+// SetContainerState sets the state ContainerList reports for a container
+// that is not running, e.g. created or dead. It defaults to exited.
+func (e *Engine) SetContainerState(containerID string, state string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if c, err := e.lookup(containerID); err == nil {
+		c.state = state
+	}
+}
+
+// ContainerWait returns the scripted exit status
+func (e *Engine) ContainerWait(ctx context.Context, containerID string, condition container.WaitCondition) (<-chan container.WaitResponse, <-chan error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ContainerWait")
+	statusCh := make(chan container.WaitResponse, 1)
+	errCh := make(chan error, 1)
+	c, err := e.lookup(containerID)
+	switch {
+	case err != nil:
+		errCh <- err
+	case c.script.WaitErr != nil:
+		errCh <- c.script.WaitErr
+	case c.script.Hang:
+		go func() {
+			select {
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+			case <-c.killed:
+				statusCh <- container.WaitResponse{StatusCode: 137}
+			}
+		}()
+	default:
+		c.running = false
+		statusCh <- container.WaitResponse{StatusCode: c.script.ExitCode}
+	}
+	return statusCh, errCh
+}
+
+// ContainerLogs returns the scripted stdout and stderr multiplexed as per
+// the Docker log stream format
+func (e *Engine) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ContainerLogs")
+	c, err := e.lookup(containerID)
+	if err != nil {
+		return nil, err
+	}
+	if c.script.LogsErr != nil {
+		return nil, c.script.LogsErr
+	}
+	var buf bytes.Buffer
+	if options.ShowStdout && c.script.Stdout != "" {
+		stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(c.script.Stdout))
+	}
+	if options.ShowStderr && c.script.Stderr != "" {
+		stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write([]byte(c.script.Stderr))
+	}
+	if c.script.StreamErr != nil {
+		return io.NopCloser(io.MultiReader(&buf, iotest.ErrReader(c.script.StreamErr))), nil
+	}
+	return io.NopCloser(&buf), nil
+}
+
+// ContainerAttach returns a connection recording the container's stdin,
+// which Stdin reports
+func (e *Engine) ContainerAttach(ctx context.Context, containerID string, options container.AttachOptions) (types.HijackedResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ContainerAttach")
+	c, err := e.lookup(containerID)
+	if err != nil {
+		return types.HijackedResponse{}, err
+	}
+	conn := &stdinConn{}
+	e.stdins[c.id] = conn
+	return types.NewHijackedResponse(conn, ""), nil
+}
+
+// Stdin returns what was written to the stdin of a container, including
+// one that has since been removed
+func (e *Engine) Stdin(containerID string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	conn, ok := e.stdins[containerID]
+	if !ok {
+		return ""
+	}
+	return conn.String()
+}
+
+// ContainerKill stops a running container
+func (e *Engine) ContainerKill(ctx context.Context, containerID, signal string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ContainerKill")
+	c, err := e.lookup(containerID)
+	if err != nil {
+		return err
+	}
+	if !c.running {
+		return errdefs.Conflict(fmt.Errorf("container %s is not running", c.id))
+	}
+	c.running = false
+	close(c.killed)
+	return nil
+}
+
+// ContainerRemove removes a container from the fake engine. Like the
+// Docker daemon, it refuses to remove a running container unless forced.
+func (e *Engine) ContainerRemove(ctx context.Context, containerID string, options container.RemoveOptions) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ContainerRemove")
+	c, err := e.lookup(containerID)
+	if err != nil {
+		return err
+	}
+	if c.running && !options.Force {
+		return errdefs.Conflict(fmt.Errorf("cannot remove container %s: container is running", c.id))
+	}
+	delete(e.containers, c.id)
+	e.removed = append(e.removed, c.id)
+	return nil
+}
+
+// ContainerList lists containers, supporting the label, name and status
+// filters
+func (e *Engine) ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ContainerList")
+	var list []types.Container
+	for i := 1; i <= e.nextID; i++ {
+		c, ok := e.containers[containerID(i)]
+		if !ok {
+			continue
+		}
+		if !options.All && !c.running {
+			continue
+		}
+		if !options.Filters.MatchKVList("label", c.labels) {
+			continue
+		}
+		if options.Filters.Contains("name") && !options.Filters.Match("name", "/"+c.name) {
+			continue
+		}
+		state := "exited"
+		if c.state != "" {
+			state = c.state
+		}
+		if c.running {
+			state = "running"
+		}
+		if options.Filters.Contains("status") && !options.Filters.ExactMatch("status", state) {
+			continue
+		}
+		list = append(list, types.Container{
+			ID:     c.id,
+			Names:  []string{"/" + c.name},
+			Image:  c.image,
+			Labels: c.labels,
+			State:  state,
+		})
+	}
+	return list, nil
+}
+
+// ImagePull records the call and returns PullOutput or PullErr
+func (e *Engine) ImagePull(ctx context.Context, refStr string, options image.PullOptions) (io.ReadCloser, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ImagePull")
+	e.pulls = append(e.pulls, PullCall{
+		Image:   refStr,
+		Options: options,
+	})
+	if e.PullErr != nil {
+		return nil, e.PullErr
+	}
+	platform, _ := shared.ParsePlatform(options.Platform)
+	e.images[refStr] = platform
+	return io.NopCloser(strings.NewReader(e.PullOutput)), nil
+}
+
+// SetDigest sets the repository digest, sha256:..., that ImageInspectWithRaw
+// reports for ref. References pinned by digest report their own.
+func (e *Engine) SetDigest(ref string, digest string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.digests[ref] = digest
+}
+
+// ImageInspectWithRaw reports images added with AddImage or pulled
+func (e *Engine) ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ImageInspectWithRaw")
+	platform, ok := e.images[imageID]
+	if !ok {
+		return types.ImageInspect{}, nil, errdefs.NotFound(fmt.Errorf("no such image: %s", imageID))
+	}
+	var repoDigests []string
+	if repo, digest, ok := strings.Cut(imageID, "@"); ok {
+		repoDigests = append(repoDigests, repo+"@"+digest)
+	} else if digest, ok := e.digests[imageID]; ok {
+		repo := imageID
+		if i := strings.LastIndex(imageID, ":"); i > strings.LastIndex(imageID, "/") {
+			repo = imageID[:i]
+		}
+		repoDigests = append(repoDigests, repo+"@"+digest)
+	}
+	return types.ImageInspect{
+		ID:           imageID,
+		RepoTags:     []string{imageID},
+		RepoDigests:  repoDigests,
+		Os:           platform.OS,
+		Architecture: platform.Arch,
+		Variant:      platform.Variant,
+	}, nil, nil
+}
+
+// CopyToContainer records the content copied to a container
+func (e *Engine) CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options container.CopyToContainerOptions) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("CopyToContainer")
+	if _, err := e.lookup(containerID); err != nil {
+		return err
+	}
+	if e.CopyErr != nil {
+		return e.CopyErr
+	}
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	e.copies = append(e.copies, CopyCall{
+		ContainerID: containerID,
+		DstPath:     dstPath,
+		Content:     b,
+	})
+	return nil
+}
+
+// CopyFromContainer returns a tar archive of the container's scripted
+// Files under srcPath, rooted at the base name of srcPath as Docker does.
+// A path with no scripted files is reported as not found.
+func (e *Engine) CopyFromContainer(ctx context.Context, containerID, srcPath string) (io.ReadCloser, container.PathStat, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("CopyFromContainer")
+	c, err := e.lookup(containerID)
+	if err != nil {
+		return nil, container.PathStat{}, err
+	}
+
+	srcPath = path.Clean(srcPath)
+	base := path.Base(srcPath)
+	var names []string
+	for name := range c.script.Files {
+		if strings.HasPrefix(name, srcPath+"/") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, container.PathStat{}, errdefs.NotFound(errors.New("path not found in fake container: " + srcPath))
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: base + "/", Mode: 0755})
+	for _, name := range names {
+		content := c.script.Files[name]
+		tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path.Join(base, strings.TrimPrefix(name, srcPath+"/")),
+			Mode:     0644,
+			Size:     int64(len(content)),
+		})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	return io.NopCloser(&buf), container.PathStat{Name: base, Mode: fs.ModeDir | 0755}, nil
+}
+
+// ServerVersion returns Version
+func (e *Engine) ServerVersion(ctx context.Context) (types.Version, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("ServerVersion")
+	return e.Version, nil
+}
+
+// DaemonHost returns Host
+func (e *Engine) DaemonHost() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.Host
+}
+
+// Info returns SystemInfo
+func (e *Engine) Info(ctx context.Context) (system.Info, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.record("Info")
+	return e.SystemInfo, nil
+}
@@ -0,0 +1,237 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// dockerHubAuthKey is the key Docker Hub credentials are stored under in
+// the Docker CLI configuration
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// dockerConfigFile represents the parts of the Docker CLI configuration,
+// ~/.docker/config.json, that hold registry credentials
+type dockerConfigFile struct {
+	Auths       map[string]dockerAuthEntry `json:"auths"`
+	CredsStore  string                     `json:"credsStore"`
+	CredHelpers map[string]string          `json:"credHelpers"`
+}
+
+type dockerAuthEntry struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+// credentialHelperOutput represents the response of a Docker credential
+// helper's get command
+type credentialHelperOutput struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// ResolveRegistryAuth looks up the credentials for the registry hosting
+// img in the Docker CLI configuration, found in $DOCKER_CONFIG or
+// ~/.docker. Credential helpers, per registry (credHelpers) or global
+// (credsStore), take precedence over credentials stored in the file
+// (auths). An empty AuthConfig is returned when the registry has no
+// credentials, so that the image is pulled anonymously. Helpers are
+// killed once ctx is done, e.g. when one waits on a locked keychain.
+func ResolveRegistryAuth(ctx context.Context, img string) (registry.AuthConfig, error) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+	domain := reference.Domain(named)
+	key := domain
+	if domain == "docker.io" {
+		key = dockerHubAuthKey
+	}
+
+	cfg, err := loadDockerConfig()
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+
+	aliases := registryAliases(domain)
+	helper := cfg.CredsStore
+	for _, alias := range aliases {
+		if h, ok := cfg.CredHelpers[alias]; ok {
+			helper = h
+			break
+		}
+	}
+	if helper != "" {
+		auth, found, err := helperAuth(ctx, helper, key)
+		if err != nil {
+			return registry.AuthConfig{}, err
+		}
+		if found {
+			return auth, nil
+		}
+	}
+
+	for k, entry := range cfg.Auths {
+		if slices.Contains(aliases, k) || slices.Contains(aliases, registryHost(k)) {
+			return entryAuth(entry, key)
+		}
+	}
+	return registry.AuthConfig{}, nil
+}
+
+// loadDockerConfig reads the Docker CLI configuration. A missing file is
+// an empty configuration.
+func loadDockerConfig() (dockerConfigFile, error) {
+	var cfg dockerConfigFile
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return cfg, nil
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid docker config: %w", err)
+	}
+	return cfg, nil
+}
+
+// helperWaitDelay bounds the wait for a killed credential helper's output
+const helperWaitDelay = time.Second
+
+// helperAuth runs docker-credential-<helper> get for serverURL. A helper
+// that is not installed, or holds no credentials for serverURL, reports
+// found as false.
+func helperAuth(ctx context.Context, helper string, serverURL string) (auth registry.AuthConfig, found bool, err error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Processes the helper started may hold its output open once it is
+	// killed
+	cmd.WaitDelay = helperWaitDelay
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return auth, false, fmt.Errorf("credential helper %s: %w", helper, ctxErr)
+		}
+		if errors.Is(err, exec.ErrNotFound) || strings.Contains(stdout.String(), "credentials not found") {
+			return auth, false, nil
+		}
+		return auth, false, fmt.Errorf("credential helper %s: %w: %s", helper, err, strings.TrimSpace(stdout.String()+stderr.String()))
+	}
+
+	var out credentialHelperOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return auth, false, fmt.Errorf("credential helper %s: %w", helper, err)
+	}
+	auth.ServerAddress = serverURL
+	if out.Username == "<token>" {
+		auth.IdentityToken = out.Secret
+	} else {
+		auth.Username = out.Username
+		auth.Password = out.Secret
+	}
+	return auth, true, nil
+}
+
+// entryAuth decodes credentials stored in the Docker CLI configuration
+func entryAuth(entry dockerAuthEntry, serverURL string) (registry.AuthConfig, error) {
+	auth := registry.AuthConfig{
+		Username:      entry.Username,
+		Password:      entry.Password,
+		IdentityToken: entry.IdentityToken,
+		RegistryToken: entry.RegistryToken,
+		ServerAddress: serverURL,
+	}
+	if entry.Auth != "" {
+		b, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return registry.AuthConfig{}, fmt.Errorf("invalid auth for %s: %w", serverURL, err)
+		}
+		user, password, ok := strings.Cut(string(b), ":")
+		if !ok {
+			return registry.AuthConfig{}, fmt.Errorf("invalid auth for %s", serverURL)
+		}
+		auth.Username, auth.Password = user, password
+	}
+	return auth, nil
+}
+
+// registryAliases returns the keys the Docker CLI configuration may store
+// a registry's entries under. Docker Hub has several, and the CLI itself
+// writes https://index.docker.io/v1/.
+func registryAliases(domain string) []string {
+	if domain == "docker.io" {
+		return []string{dockerHubAuthKey, "index.docker.io", "docker.io", "registry-1.docker.io"}
+	}
+	return []string{domain}
+}
+
+// registryHost strips the scheme and path from a key of auths, e.g.
+// https://registry.example.com/v1/
+func registryHost(key string) string {
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	host, _, _ := strings.Cut(key, "/")
+	return host
+}
+
+// registryAuth returns the encoded credentials for pulling img, from
+// WithRegistryAuth or else the Docker CLI configuration
+func (c Config) registryAuth(ctx context.Context, img string) (string, error) {
+	auth := c.RegistryAuth
+	if auth == nil {
+		resolved, err := ResolveRegistryAuth(ctx, img)
+		if err != nil {
+			return "", err
+		}
+		if resolved == (registry.AuthConfig{}) {
+			return "", nil
+		}
+		auth = &resolved
+	}
+	return registry.EncodeAuthConfig(*auth)
+}
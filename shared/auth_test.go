@@ -0,0 +1,159 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/registry"
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+const dockerConfig = `{
+	"auths": {
+		"https://index.docker.io/v1/": {"auth": "aHViOmh1Yi1zZWNyZXQ="},
+		"https://registry.example.com": {"username": "ci", "password": "ci-secret"}
+	},
+	"credHelpers": {
+		"ghcr.io": "narwhaltest",
+		"locked.example.com": "narwhaltest"
+	}
+}`
+
+const credentialHelper = `#!/bin/sh
+read server
+if [ "$server" = "ghcr.io" ]; then
+	echo '{"ServerURL":"ghcr.io","Username":"<token>","Secret":"ghcr-token"}'
+	exit 0
+fi
+if [ "$server" = "https://index.docker.io/v1/" ]; then
+	echo '{"ServerURL":"https://index.docker.io/v1/","Username":"hub-helper","Secret":"hub-helper-secret"}'
+	exit 0
+fi
+if [ "$server" = "locked.example.com" ]; then
+	sleep 10
+fi
+echo "credentials not found in native keychain"
+exit 1
+`
+
+// setDockerConfig points the Docker CLI configuration and credential
+// helpers at a temporary directory
+func setDockerConfig(t *testing.T, config string) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docker-credential-narwhaltest"), []byte(credentialHelper), 0700))
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestResolveRegistryAuth(t *testing.T) {
+	setDockerConfig(t, dockerConfig)
+
+	testcases := []struct {
+		img  string
+		want registry.AuthConfig
+	}{
+		{
+			img:  "ethereum/solc:0.8.28",
+			want: registry.AuthConfig{Username: "hub", Password: "hub-secret", ServerAddress: "https://index.docker.io/v1/"},
+		},
+		{
+			img:  "registry.example.com/narwhal/protoc:current",
+			want: registry.AuthConfig{Username: "ci", Password: "ci-secret", ServerAddress: "registry.example.com"},
+		},
+		{
+			img:  "ghcr.io/paulwizviz/solc:0.8.28",
+			want: registry.AuthConfig{IdentityToken: "ghcr-token", ServerAddress: "ghcr.io"},
+		},
+		{
+			img:  "quay.io/narwhal/protoc:current",
+			want: registry.AuthConfig{},
+		},
+	}
+	for i, tc := range testcases {
+		got, err := shared.ResolveRegistryAuth(context.Background(), tc.img)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
+
+func TestResolveRegistryAuthDockerHubHelper(t *testing.T) {
+	testcases := []struct {
+		key string
+	}{
+		{key: "https://index.docker.io/v1/"},
+		{key: "index.docker.io"},
+		{key: "docker.io"},
+	}
+	for i, tc := range testcases {
+		setDockerConfig(t, fmt.Sprintf(`{"credHelpers": {%q: "narwhaltest"}}`, tc.key))
+		got, err := shared.ResolveRegistryAuth(context.Background(), "ethereum/solc:0.8.28")
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		want := registry.AuthConfig{Username: "hub-helper", Password: "hub-helper-secret", ServerAddress: "https://index.docker.io/v1/"}
+		assert.Equal(t, want, got, fmt.Sprintf("Case: %d", i))
+	}
+}
+
+func TestResolveRegistryAuthCancel(t *testing.T) {
+	setDockerConfig(t, dockerConfig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := shared.ResolveRegistryAuth(ctx, "locked.example.com/narwhal/protoc:current")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), fmt.Sprintf("Got: %v", err))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestPullImageRegistryAuth(t *testing.T) {
+	setDockerConfig(t, dockerConfig)
+
+	engine := narwhaltest.NewEngine()
+	err := shared.PullImage(context.Background(), engine, "registry.example.com/narwhal/protoc:current", shared.PlatformLinuxAMD64())
+	assert.NoError(t, err)
+
+	explicit := registry.AuthConfig{Username: "mirror", Password: "mirror-secret", ServerAddress: "mirror.local"}
+	err = shared.PullImage(context.Background(), engine, "mirror.local/ethereum/solc:0.8.28", shared.PlatformLinuxAMD64(), shared.WithRegistryAuth(explicit))
+	assert.NoError(t, err)
+
+	err = shared.PullImage(context.Background(), engine, "quay.io/narwhal/protoc:current", shared.PlatformLinuxAMD64())
+	assert.NoError(t, err)
+
+	pulls := engine.Pulls()
+	if assert.Len(t, pulls, 3) {
+		auth, err := registry.DecodeAuthConfig(pulls[0].Options.RegistryAuth)
+		assert.NoError(t, err)
+		assert.Equal(t, "ci", auth.Username)
+
+		auth, err = registry.DecodeAuthConfig(pulls[1].Options.RegistryAuth)
+		assert.NoError(t, err)
+		assert.Equal(t, explicit, *auth)
+
+		assert.Equal(t, "", pulls[2].Options.RegistryAuth)
+	}
+}
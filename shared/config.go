@@ -0,0 +1,271 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/docker/docker/api/types/registry"
+)
+
+// Config represents configuration applied to tools, their runs and
+// image pulls
+type Config struct {
+	// Engine runs the tool containers. Tool constructors default to a
+	// Docker client configured from the environment.
+	Engine Engine
+	// Image is the reference of the tool image
+	Image string
+	// Platform is the platform of the tool image
+	Platform DockerPlatformConfig
+	// PullPolicy determines when the tool image is pulled
+	PullPolicy PullPolicy
+	// PullAttempts is the number of times a failing pull is tried, waiting
+	// PullBackoff before the first retry and twice as long before each
+	// subsequent one
+	PullAttempts int
+	PullBackoff  time.Duration
+
+	// Stdout receives the tool's standard output and image pull messages.
+	// Defaults to os.Stdout unless a Logger is set.
+	Stdout io.Writer
+	// Stderr receives the tool's standard error. Defaults to os.Stderr
+	// unless a Logger is set.
+	Stderr io.Writer
+	// Logger receives output line by line as structured records
+	Logger *slog.Logger
+	// Capture records the tool's standard output in the Result. Standard
+	// error is always recorded.
+	Capture bool
+	// RunID is recorded in LabelRunID of every container, e.g. to relate
+	// containers to a CI job
+	RunID string
+	// Timeout bounds each tool run. Zero means no timeout other than the
+	// caller's context.
+	Timeout time.Duration
+	// User runs tool containers as user, e.g. uid:gid. Defaults to
+	// HostUser. Empty means the image's default user.
+	User string
+	// ChownImage, when set, runs tools as the image's default user and then
+	// hands their outputs over to User with a helper container from this
+	// image. See WithChownOutputs.
+	ChownImage string
+	// Transfer determines how mounts reach tool containers. Defaults to
+	// TransferAuto.
+	Transfer TransferMode
+	// RegistryAuth authenticates image pulls. When nil, credentials are
+	// resolved from the Docker CLI configuration.
+	RegistryAuth *registry.AuthConfig
+	// PullProgress receives the progress events of image pulls
+	PullProgress func(PullEvent)
+	// OnResult receives the Result of every run whose container was
+	// created, e.g. to record the ImageDigest of tool calls
+	OnResult func(Result)
+	// Lockfile pins image references to digests, verified when images
+	// are ensured
+	Lockfile Lockfile
+	// Runtime describes the engine. It is detected on first use when nil.
+	Runtime *EngineRuntime
+	// Sandbox is applied to every tool container. Defaults to
+	// DefaultSandbox.
+	Sandbox Sandbox
+	// KeepContainer leaves tool containers in place after a run, e.g. for
+	// debugging. By default they are removed.
+	KeepContainer bool
+}
+
+// Option represents a function to configure a Config
+type Option func(*Config)
+
+// WithClient sets the container engine, e.g. a Docker client
+func WithClient(engine Engine) Option {
+	return func(c *Config) {
+		c.Engine = engine
+	}
+}
+
+// WithImage sets the reference of the tool image
+func WithImage(img string) Option {
+	return func(c *Config) {
+		c.Image = img
+	}
+}
+
+// WithPlatform sets the platform of the tool image
+func WithPlatform(p DockerPlatformConfig) Option {
+	return func(c *Config) {
+		c.Platform = p
+	}
+}
+
+// WithPullRetry tries failing pulls up to attempts times, waiting backoff
+// before the first retry and doubling it for every subsequent one.
+// Defaults to DefaultPullAttempts and DefaultPullBackoff.
+func WithPullRetry(attempts int, backoff time.Duration) Option {
+	return func(c *Config) {
+		c.PullAttempts = attempts
+		c.PullBackoff = backoff
+	}
+}
+
+// WithPullPolicy sets when the tool image is pulled
+func WithPullPolicy(policy PullPolicy) Option {
+	return func(c *Config) {
+		c.PullPolicy = policy
+	}
+}
+
+// WithStdout routes standard output to w
+func WithStdout(w io.Writer) Option {
+	return func(c *Config) {
+		c.Stdout = w
+	}
+}
+
+// WithStderr routes standard error to w
+func WithStderr(w io.Writer) Option {
+	return func(c *Config) {
+		c.Stderr = w
+	}
+}
+
+// WithLogger routes output to logger, one record per line
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithCapture records standard output in the Result of a run
+func WithCapture() Option {
+	return func(c *Config) {
+		c.Capture = true
+	}
+}
+
+// WithRunID records id in LabelRunID of every container
+func WithRunID(id string) Option {
+	return func(c *Config) {
+		c.RunID = id
+	}
+}
+
+// WithTimeout bounds each tool run to d
+func WithTimeout(d time.Duration) Option {
+	return func(c *Config) {
+		c.Timeout = d
+	}
+}
+
+// WithUser runs tool containers as user, e.g. 1000:1000, in place of
+// HostUser. Use "" for the image's default user.
+func WithUser(user string) Option {
+	return func(c *Config) {
+		c.User = user
+	}
+}
+
+// WithChownOutputs is a fallback for tool images that cannot run as an
+// arbitrary user. Tools run as the image's default user and their outputs
+// are then handed over to the configured user by a helper container from
+// img, e.g. DefaultChownImage.
+func WithChownOutputs(img string) Option {
+	return func(c *Config) {
+		c.ChownImage = img
+	}
+}
+
+// WithTransfer sets how mounts reach tool containers, e.g. TransferCopy
+// for a remote daemon that TransferAuto does not detect
+func WithTransfer(mode TransferMode) Option {
+	return func(c *Config) {
+		c.Transfer = mode
+	}
+}
+
+// WithRegistryAuth authenticates image pulls with auth in place of the
+// credentials in the Docker CLI configuration
+func WithRegistryAuth(auth registry.AuthConfig) Option {
+	return func(c *Config) {
+		c.RegistryAuth = &auth
+	}
+}
+
+// WithPullProgress passes the progress events of image pulls to fn, e.g.
+// to render a progress bar
+func WithPullProgress(fn func(PullEvent)) Option {
+	return func(c *Config) {
+		c.PullProgress = fn
+	}
+}
+
+// WithOnResult passes the Result of every tool run to fn, including runs
+// that fail once their container exists
+func WithOnResult(fn func(Result)) Option {
+	return func(c *Config) {
+		c.OnResult = fn
+	}
+}
+
+// WithLockfile fails preparing the tool image, i.e. Prepare or the first
+// tool call, when an image pinned in lock resolves to a different digest
+func WithLockfile(lock Lockfile) Option {
+	return func(c *Config) {
+		c.Lockfile = lock
+	}
+}
+
+// WithRuntime sets the engine's runtime in place of detecting it with
+// DetectRuntime
+func WithRuntime(rt EngineRuntime) Option {
+	return func(c *Config) {
+		c.Runtime = &rt
+	}
+}
+
+// WithSandbox applies the sandbox profile to every tool container in
+// place of DefaultSandbox. Use Sandbox{} to apply no restrictions.
+func WithSandbox(s Sandbox) Option {
+	return func(c *Config) {
+		c.Sandbox = s
+	}
+}
+
+// WithKeepContainer leaves tool containers in place after a run
+func WithKeepContainer() Option {
+	return func(c *Config) {
+		c.KeepContainer = true
+	}
+}
+
+// NewConfig returns a Config with defaults and opts applied
+func NewConfig(opts ...Option) Config {
+	c := Config{
+		PullAttempts: DefaultPullAttempts,
+		PullBackoff:  DefaultPullBackoff,
+		User:         HostUser(),
+		Sandbox:      DefaultSandbox(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EngineKind represents the product behind a Docker-compatible API
+type EngineKind int
+
+const (
+	// EngineDocker is Docker Engine, the default
+	EngineDocker EngineKind = iota
+	// EnginePodman is Podman serving its Docker-compatible API
+	EnginePodman
+)
+
+func (k EngineKind) String() string {
+	switch k {
+	case EngineDocker:
+		return "Docker"
+	case EnginePodman:
+		return "Podman"
+	default:
+		return "Unknown"
+	}
+}
+
+// EngineRuntime describes the engine behind an Engine, as far as it
+// changes how tool containers are run
+type EngineRuntime struct {
+	Kind EngineKind
+	// Rootless is set when the engine runs as an unprivileged user, in
+	// which case the container's root maps to that user
+	Rootless bool
+	// SELinux is set when the engine enforces SELinux labels, in which
+	// case bind mounts are relabelled
+	SELinux bool
+}
+
+// DetectRuntime identifies the engine behind cli from its version and
+// info
+func DetectRuntime(ctx context.Context, cli Engine) (EngineRuntime, error) {
+	var rt EngineRuntime
+
+	version, err := cli.ServerVersion(ctx)
+	if err != nil {
+		return rt, err
+	}
+	for _, c := range version.Components {
+		if strings.HasPrefix(strings.ToLower(c.Name), "podman") {
+			rt.Kind = EnginePodman
+		}
+	}
+
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return rt, err
+	}
+	for _, opt := range info.SecurityOptions {
+		// Options are reported as e.g. name=seccomp,profile=default
+		for _, kv := range strings.Split(opt, ",") {
+			switch kv {
+			case "name=rootless":
+				rt.Rootless = true
+			case "name=selinux":
+				rt.SELinux = true
+			}
+		}
+	}
+	return rt, nil
+}
+
+// DiscoverHost returns the address of a local engine socket, for use as
+// DOCKER_HOST. DOCKER_HOST itself takes precedence. Otherwise the rootful
+// Docker socket, the rootless Docker socket and then the rootless and
+// rootful Podman sockets are tried in turn. "" is returned if none exist.
+func DiscoverHost() string {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return host
+	}
+	for _, socket := range socketCandidates() {
+		if fi, err := os.Stat(socket); err == nil && fi.Mode().Type() == os.ModeSocket {
+			return "unix://" + socket
+		}
+	}
+	return ""
+}
+
+func socketCandidates() []string {
+	sockets := []string{"/var/run/docker.sock"}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		sockets = append(sockets,
+			filepath.Join(dir, "docker.sock"),
+			filepath.Join(dir, "podman", "podman.sock"),
+		)
+	}
+	return append(sockets, "/run/podman/podman.sock")
+}
+
+// mapUser adapts user to the engine. A rootless engine already maps the
+// container's root to the calling user, whereas running as the caller's
+// uid would map to one of its subordinate ids.
+func (rt EngineRuntime) mapUser(user string) string {
+	if rt.Rootless && user != "" && user == HostUser() {
+		return ""
+	}
+	return user
+}
+
+// bind returns a bind in the form used by HostConfig.Binds, relabelled
+// under SELinux. Read-only inputs, e.g. a project shared by concurrent
+// runs, get the shared label; outputs get one private to the container.
+//
+// Unlike a mount, a bind whose source is missing is created by the engine
+// as a root-owned directory, and a path containing a colon is split into
+// the wrong fields, so both are rejected.
+func (rt EngineRuntime) bind(m Mount) (string, error) {
+	if strings.Contains(m.Source, ":") || strings.Contains(m.Target, ":") {
+		return "", fmt.Errorf("cannot bind %s to %s: paths containing ':' are not supported under SELinux", m.Source, m.Target)
+	}
+	if _, err := os.Stat(m.Source); err != nil {
+		return "", fmt.Errorf("cannot bind %s: %w", m.Source, err)
+	}
+	var opts []string
+	if m.ReadOnly {
+		opts = append(opts, "ro")
+	}
+	if rt.SELinux {
+		if m.ReadOnly {
+			opts = append(opts, "z")
+		} else {
+			opts = append(opts, "Z")
+		}
+	}
+	bind := m.Source + ":" + m.Target
+	if len(opts) > 0 {
+		bind += ":" + strings.Join(opts, ",")
+	}
+	return bind, nil
+}
+
+// defaultVariant reports whether variant is assumed by engines that
+// report no variant for arch, as Podman does for e.g. linux/arm64
+func defaultVariant(arch string, variant string) bool {
+	switch arch {
+	case archARM64:
+		return variant == "v8"
+	case archARM:
+		return variant == "v7"
+	}
+	return variant == ""
+}
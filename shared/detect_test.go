@@ -0,0 +1,184 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/system"
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func podman() types.Version {
+	return types.Version{Components: []types.ComponentVersion{{Name: "Podman Engine", Version: "5.2.2"}}}
+}
+
+func TestDetectRuntime(t *testing.T) {
+	testcases := []struct {
+		version  types.Version
+		security []string
+		want     shared.EngineRuntime
+	}{
+		{
+			version:  types.Version{Components: []types.ComponentVersion{{Name: "Engine", Version: "27.4.1"}}},
+			security: []string{"name=apparmor", "name=seccomp,profile=builtin"},
+			want:     shared.EngineRuntime{Kind: shared.EngineDocker},
+		},
+		{
+			version:  types.Version{Components: []types.ComponentVersion{{Name: "Engine", Version: "27.4.1"}}},
+			security: []string{"name=seccomp,profile=builtin", "name=rootless", "name=cgroupns"},
+			want:     shared.EngineRuntime{Kind: shared.EngineDocker, Rootless: true},
+		},
+		{
+			version:  podman(),
+			security: []string{"name=seccomp,profile=/usr/share/containers/seccomp.json", "name=rootless", "name=selinux"},
+			want:     shared.EngineRuntime{Kind: shared.EnginePodman, Rootless: true, SELinux: true},
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		engine.Version = tc.version
+		engine.SystemInfo = system.Info{SecurityOptions: tc.security}
+		got, err := shared.DetectRuntime(context.Background(), engine)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
+
+func TestRunnerSELinuxBindInvalid(t *testing.T) {
+	dir := t.TempDir()
+	colon := filepath.Join(dir, "a:b")
+	assert.NoError(t, os.Mkdir(colon, 0755))
+
+	testcases := []struct {
+		mount shared.Mount
+	}{
+		{mount: shared.Mount{Source: filepath.Join(dir, "missing"), Target: "/opt/abi"}},
+		{mount: shared.Mount{Source: colon, Target: "/opt/abi"}},
+		{mount: shared.Mount{Source: dir, Target: "/opt/a:b"}},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		runner := shared.NewRunner(engine, shared.WithRuntime(shared.EngineRuntime{SELinux: true}))
+		_, err := runner.Run(context.Background(), shared.RunSpec{Image: "tool", Mounts: []shared.Mount{tc.mount}})
+		assert.True(t, errors.Is(err, shared.ErrCreateContainer), fmt.Sprintf("Case: %d Got: %v", i, err))
+		assert.Empty(t, engine.Creates(), fmt.Sprintf("Case: %d", i))
+	}
+	_, err := os.Stat(filepath.Join(dir, "missing"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestRunnerRuntime(t *testing.T) {
+	src, out := t.TempDir(), t.TempDir()
+	spec := shared.RunSpec{
+		Name:   "solc",
+		Image:  "ethereum/solc:0.8.28",
+		Mounts: []shared.Mount{{Source: src, Target: "/opt/solidity", ReadOnly: true}, {Source: out, Target: "/opt/abi"}},
+	}
+
+	// Rootless Podman under SELinux
+	engine := narwhaltest.NewEngine()
+	engine.Version = podman()
+	engine.SystemInfo = system.Info{SecurityOptions: []string{"name=rootless", "name=selinux"}}
+	runner := shared.NewRunner(engine, shared.WithUser(shared.HostUser()), shared.WithChownOutputs(shared.DefaultChownImage))
+	_, err := runner.Run(context.Background(), spec)
+	assert.NoError(t, err)
+	creates := engine.Creates()
+	if assert.Len(t, creates, 1) {
+		assert.Equal(t, "", creates[0].Config.User)
+		assert.Empty(t, creates[0].HostConfig.Mounts)
+		assert.Equal(t, []string{src + ":/opt/solidity:ro,z", out + ":/opt/abi:Z"}, creates[0].HostConfig.Binds)
+	}
+
+	// An explicit runtime skips detection
+	engine = narwhaltest.NewEngine()
+	runner = shared.NewRunner(engine, shared.WithUser("1000:1000"), shared.WithRuntime(shared.EngineRuntime{Kind: shared.EnginePodman}))
+	_, err = runner.Run(context.Background(), spec)
+	assert.NoError(t, err)
+	assert.NotContains(t, engine.Calls(), "ServerVersion")
+	assert.Equal(t, "1000:1000", engine.Creates()[0].Config.User)
+	assert.Len(t, engine.Creates()[0].HostConfig.Mounts, 2)
+}
+
+func TestEnsureImageDefaultVariant(t *testing.T) {
+	const img = "ethereum/solc:0.8.28"
+
+	testcases := []struct {
+		local     shared.DockerPlatformConfig
+		platform  shared.DockerPlatformConfig
+		wantPulls int
+	}{
+		{
+			local:     shared.DockerPlatformConfig{OS: "linux", Arch: "arm64"},
+			platform:  shared.DockerPlatformConfig{OS: "linux", Arch: "arm64", Variant: "v8"},
+			wantPulls: 0,
+		},
+		{
+			local:     shared.DockerPlatformConfig{OS: "linux", Arch: "arm"},
+			platform:  shared.DockerPlatformConfig{OS: "linux", Arch: "arm", Variant: "v6"},
+			wantPulls: 1,
+		},
+		{
+			local:     shared.DockerPlatformConfig{OS: "linux", Arch: "arm", Variant: "v6"},
+			platform:  shared.DockerPlatformConfig{OS: "linux", Arch: "arm", Variant: "v7"},
+			wantPulls: 1,
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		engine.AddImage(img, tc.local)
+		err := shared.EnsureImage(context.Background(), engine, img, tc.platform, shared.PullIfNotPresent)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		assert.Len(t, engine.Pulls(), tc.wantPulls, fmt.Sprintf("Case: %d", i))
+	}
+}
+
+func TestDiscoverHost(t *testing.T) {
+	for _, socket := range []string{"/var/run/docker.sock", "/run/podman/podman.sock"} {
+		if _, err := os.Stat(socket); err == nil {
+			t.Skip("rootful engine socket present:", socket)
+		}
+	}
+
+	t.Setenv("DOCKER_HOST", "tcp://docker:2376")
+	assert.Equal(t, "tcp://docker:2376", shared.DiscoverHost())
+
+	dir := t.TempDir()
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	assert.Equal(t, "", shared.DiscoverHost())
+
+	socket := filepath.Join(dir, "podman", "podman.sock")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(socket), 0700))
+	l, err := net.Listen("unix", socket)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer l.Close()
+	assert.Equal(t, "unix://"+socket, shared.DiscoverHost())
+}
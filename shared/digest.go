@@ -0,0 +1,132 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// ErrDigestMismatch represents an image that does not match its pinned
+// digest
+var ErrDigestMismatch = errors.New("image digest mismatch")
+
+// DigestMismatchError represents an image whose digest differs from the
+// one pinned in a Lockfile
+type DigestMismatchError struct {
+	Image string
+	Want  string
+	Got   string
+}
+
+func (e *DigestMismatchError) Error() string {
+	return fmt.Sprintf("%v: %s: want %s got %s", ErrDigestMismatch, e.Image, e.Want, e.Got)
+}
+
+func (e *DigestMismatchError) Unwrap() error {
+	return ErrDigestMismatch
+}
+
+// ImageRef joins a repository with a tag, or with a digest when
+// tagOrDigest is of the form sha256:..., so that tool constructors taking
+// a tag accept a pinned digest too
+func ImageRef(repo string, tagOrDigest string) string {
+	if strings.HasPrefix(tagOrDigest, "sha256:") {
+		return repo + "@" + tagOrDigest
+	}
+	return repo + ":" + tagOrDigest
+}
+
+// ImageDigest returns the repository digest, sha256:..., of a local
+// image. For a reference pinned by digest, the pinned digest is returned.
+// An image without a repository digest, e.g. one built locally, yields "".
+func ImageDigest(ctx context.Context, cli Engine, img string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return "", err
+	}
+	if canonical, ok := named.(reference.Canonical); ok {
+		return canonical.Digest().String(), nil
+	}
+
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, img)
+	if err != nil {
+		return "", err
+	}
+	for _, repoDigest := range inspect.RepoDigests {
+		rd, err := reference.ParseNormalizedNamed(repoDigest)
+		if err != nil {
+			continue
+		}
+		canonical, ok := rd.(reference.Canonical)
+		if ok && rd.Name() == named.Name() {
+			return canonical.Digest().String(), nil
+		}
+	}
+	return "", nil
+}
+
+// Lockfile pins image references, e.g. ethereum/solc:0.8.28, to the
+// digests they must resolve to
+type Lockfile map[string]string
+
+// LoadLockfile reads a Lockfile stored as a JSON object
+func LoadLockfile(name string) (Lockfile, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %w", name, err)
+	}
+	return lock, nil
+}
+
+// Save writes the Lockfile as a JSON object
+func (l Lockfile) Save(name string) error {
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(b, '\n'), 0644)
+}
+
+// verifyDigest checks a local image against its entry in the Lockfile.
+// Images without an entry are not checked.
+func (l Lockfile) verifyDigest(ctx context.Context, cli Engine, img string) error {
+	want, ok := l[img]
+	if !ok {
+		return nil
+	}
+	got, err := ImageDigest(ctx, cli, img)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return &DigestMismatchError{Image: img, Want: want, Got: got}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	pinnedDigest = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	otherDigest  = "sha256:fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"
+)
+
+func TestImageRef(t *testing.T) {
+	testcases := []struct {
+		repo        string
+		tagOrDigest string
+		want        string
+	}{
+		{repo: "ethereum/solc", tagOrDigest: "0.8.28", want: "ethereum/solc:0.8.28"},
+		{repo: "ethereum/solc", tagOrDigest: pinnedDigest, want: "ethereum/solc@" + pinnedDigest},
+	}
+	for i, tc := range testcases {
+		got := shared.ImageRef(tc.repo, tc.tagOrDigest)
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
+
+func TestImageDigest(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	engine.AddImage("ethereum/solc:0.8.28", shared.PlatformLinuxAMD64())
+	engine.SetDigest("ethereum/solc:0.8.28", pinnedDigest)
+	engine.AddImage("narwhal/protoc:current", shared.PlatformLinuxAMD64())
+
+	testcases := []struct {
+		img  string
+		want string
+	}{
+		{img: "ethereum/solc:0.8.28", want: pinnedDigest},
+		{img: "ethereum/solc@" + otherDigest, want: otherDigest},
+		{img: "narwhal/protoc:current", want: ""},
+	}
+	for i, tc := range testcases {
+		got, err := shared.ImageDigest(context.Background(), engine, tc.img)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
+
+func TestEnsureImageLockfile(t *testing.T) {
+	const img = "ethereum/solc:0.8.28"
+
+	name := filepath.Join(t.TempDir(), "narwhal.lock")
+	assert.NoError(t, shared.Lockfile{img: pinnedDigest}.Save(name))
+	lock, err := shared.LoadLockfile(name)
+	assert.NoError(t, err)
+
+	testcases := []struct {
+		digest  string
+		wantErr error
+	}{
+		{digest: pinnedDigest, wantErr: nil},
+		{digest: otherDigest, wantErr: shared.ErrDigestMismatch},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		engine.AddImage(img, shared.PlatformLinuxAMD64())
+		engine.SetDigest(img, tc.digest)
+		err := shared.EnsureImage(context.Background(), engine, img, shared.PlatformLinuxAMD64(), shared.PullIfNotPresent, shared.WithLockfile(lock))
+		assert.True(t, errors.Is(err, tc.wantErr), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.wantErr, err))
+	}
+}
+
+func TestRunnerImageDigest(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	engine.AddImage("ethereum/solc:0.8.28", shared.PlatformLinuxAMD64())
+	engine.SetDigest("ethereum/solc:0.8.28", pinnedDigest)
+
+	var observed shared.Result
+	runner := shared.NewRunner(engine, shared.WithOnResult(func(r shared.Result) { observed = r }))
+	result, err := runner.Run(context.Background(), shared.RunSpec{Image: "ethereum/solc:0.8.28"})
+	assert.NoError(t, err)
+	assert.Equal(t, pinnedDigest, result.ImageDigest)
+	assert.Equal(t, result, observed)
+}
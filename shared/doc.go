@@ -24,4 +24,6 @@
 // * Configuration constants
 //
 // * Support operations for all packages in this module
+//
+// * Runner to create, start and wait on containerised tools
 package shared
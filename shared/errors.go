@@ -21,6 +21,10 @@ package shared
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 )
 
 var (
@@ -32,38 +36,205 @@ var (
 	ErrStartContainer = errors.New("unable to start a container")
 	// ErrRemoveContainer represents error removing container
 	ErrRemoveContainer = errors.New("unable to remove a container")
+	// ErrListContainers represents error listing containers
+	ErrListContainers = errors.New("unable to list containers")
 	// ErrContainerLog represents error instantiate a container log
 	ErrContainerLog = errors.New("unable to instantiate container log")
 	// ErrPullImage represents error pulling an image
 	ErrPullImage = errors.New("unable to pull image")
+	// ErrAttachContainer represents error attaching to a container
+	ErrAttachContainer = errors.New("unable to attach to a container")
+	// ErrCopyToContainer represents error copying inputs into a container
+	ErrCopyToContainer = errors.New("unable to copy to a container")
+	// ErrCopyFromContainer represents error copying outputs from a container
+	ErrCopyFromContainer = errors.New("unable to copy from a container")
+	// ErrWaitContainer represents error waiting for a container to stop
+	ErrWaitContainer = errors.New("unable to wait for a container")
+	// ErrInvalidPlatform represents an unsupported or malformed platform
+	ErrInvalidPlatform = errors.New("invalid platform")
+	// ErrImageNotFound represents an image not available locally
+	ErrImageNotFound = errors.New("image not found")
+	// ErrToolFailed represents a containerised tool exiting with non-zero status
+	ErrToolFailed = errors.New("tool exited with non-zero status")
+	// ErrToolTimeout represents a containerised tool killed because its run
+	// timed out or was cancelled
+	ErrToolTimeout = errors.New("tool run timed out or was cancelled")
+)
+
+// Operations reported in ToolError.Op
+const (
+	OpInstantiateClient = "instantiate client"
+	OpPullImage         = "pull image"
+	OpCreateContainer   = "create container"
+	OpStartContainer    = "start container"
+	OpContainerLog      = "container log"
+	OpAttachContainer   = "attach container"
+	OpCopyToContainer   = "copy to container"
+	OpCopyFromContainer = "copy from container"
+	OpWaitContainer     = "wait container"
+	OpRemoveContainer   = "remove container"
+	OpListContainers    = "list containers"
+	OpRunTool           = "run tool"
+	OpTimeout           = "timeout"
 )
 
+var opErrors = map[string]error{
+	OpInstantiateClient: ErrInstantiateClient,
+	OpPullImage:         ErrPullImage,
+	OpCreateContainer:   ErrCreateContainer,
+	OpStartContainer:    ErrStartContainer,
+	OpContainerLog:      ErrContainerLog,
+	OpAttachContainer:   ErrAttachContainer,
+	OpCopyToContainer:   ErrCopyToContainer,
+	OpCopyFromContainer: ErrCopyFromContainer,
+	OpWaitContainer:     ErrWaitContainer,
+	OpRemoveContainer:   ErrRemoveContainer,
+	OpListContainers:    ErrListContainers,
+	OpRunTool:           ErrToolFailed,
+	OpTimeout:           ErrToolTimeout,
+}
+
+// ToolError represents a failure to run a containerised tool. It matches
+// the sentinel error of its Op with errors.Is, e.g. ErrCreateContainer,
+// and unwraps to the underlying error, e.g. from the Docker SDK.
+type ToolError struct {
+	// Op is the operation that failed, e.g. OpCreateContainer
+	Op string
+	// Package and Func identify where the operation was triggered
+	Package string
+	Func    string
+	// ContainerID is set once the container is created
+	ContainerID string
+	// ExitCode and Stderr are set when the tool ran, see OpRunTool
+	ExitCode int64
+	Stderr   string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ToolError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s.%s: %v", e.Package, e.Func, opErrors[e.Op])
+	if e.ContainerID != "" {
+		fmt.Fprintf(&b, ": container %s", e.ContainerID)
+	}
+	if e.Op == OpRunTool {
+		fmt.Fprintf(&b, ": exit code %d", e.ExitCode)
+	}
+	if e.Err != nil && e.Err != opErrors[e.Op] {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	if stderr := strings.TrimSpace(e.Stderr); stderr != "" {
+		fmt.Fprintf(&b, ": %s", stderr)
+	}
+	return b.String()
+}
+
+// Is reports whether target is the sentinel error of the operation
+func (e *ToolError) Is(target error) bool {
+	return target == opErrors[e.Op]
+}
+
+// Unwrap returns the underlying error
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+func toolErr(op string, err error, pkg string, fname string) *ToolError {
+	return &ToolError{
+		Op:      op,
+		Package: pkg,
+		Func:    fname,
+		Err:     err,
+	}
+}
+
+// IsNotFound reports whether err is caused by a missing container or image
+func IsNotFound(err error) bool {
+	return errdefs.IsNotFound(err) || errors.Is(err, ErrImageNotFound)
+}
+
+// IsConflict reports whether err is caused by a conflict, e.g. a container
+// name already in use or removing a running container
+func IsConflict(err error) bool {
+	return errdefs.IsConflict(err)
+}
+
+// IsDaemonUnreachable reports whether err is caused by failing to connect
+// to the container engine's daemon
+func IsDaemonUnreachable(err error) bool {
+	return client.IsErrConnectionFailed(err) || errdefs.IsUnavailable(err)
+}
+
 // InstantiateClientErr returns an error handler instatiating a client
 func InstantiateClientErr(err error, pkg string, fname string) error {
-	return fmt.Errorf("%w-%s-%s-%v", ErrInstantiateClient, pkg, fname, err)
+	return toolErr(OpInstantiateClient, err, pkg, fname)
 }
 
 // CreateContainerErr returns an error handler creating a container
 func CreateContainerErr(err error, pkg string, fname string) error {
-	return fmt.Errorf("%w-%s-%s-%v", ErrCreateContainer, pkg, fname, err)
+	return toolErr(OpCreateContainer, err, pkg, fname)
 }
 
 // StartContainerErr returns an error handler starting a container
 func StartContainerErr(err error, pkg string, fname string) error {
-	return fmt.Errorf("%w-%s-%s-%v", ErrStartContainer, pkg, fname, err)
+	return toolErr(OpStartContainer, err, pkg, fname)
 }
 
 // RemoveContainerErr returns an error handler removing a container
 func RemoveContainerErr(err error, pkg string, fname string) error {
-	return fmt.Errorf("%w-%s-%s-%v", ErrRemoveContainer, pkg, fname, err)
+	return toolErr(OpRemoveContainer, err, pkg, fname)
+}
+
+// ListContainersErr returns an error handler listing containers
+func ListContainersErr(err error, pkg string, fname string) error {
+	return toolErr(OpListContainers, err, pkg, fname)
 }
 
-// ContainerLogErr returns an error handler removing a container
+// ContainerLogErr returns an error handler instantiating a container log
 func ContainerLogErr(err error, pkg string, fname string) error {
-	return fmt.Errorf("%w-%s-%s-%v", ErrRemoveContainer, pkg, fname, err)
+	return toolErr(OpContainerLog, err, pkg, fname)
 }
 
 // PullImageError returns an error handler pulling image
 func PullImageError(err error, pkg string, fname string) error {
-	return fmt.Errorf("%w-%s-%s-%v", ErrPullImage, pkg, fname, err)
+	return toolErr(OpPullImage, err, pkg, fname)
+}
+
+// WaitContainerErr returns an error handler waiting for a container
+func WaitContainerErr(err error, pkg string, fname string) error {
+	return toolErr(OpWaitContainer, err, pkg, fname)
+}
+
+// ToolExitErr returns an error handler for a tool that exited with non-zero status
+func ToolExitErr(containerID string, exitCode int64, stderr string, pkg string, fname string) error {
+	e := toolErr(OpRunTool, ErrToolFailed, pkg, fname)
+	e.ContainerID = containerID
+	e.ExitCode = exitCode
+	e.Stderr = stderr
+	return e
+}
+
+// ImageMissingError represents an image that is not available locally
+// and is not allowed to be pulled
+type ImageMissingError struct {
+	Image    string
+	Platform DockerPlatformConfig
+}
+
+func (e *ImageMissingError) Error() string {
+	return fmt.Sprintf("%v: %s for %s", ErrImageNotFound, e.Image, e.Platform)
+}
+
+// Unwrap enables errors.Is(err, ErrImageNotFound)
+func (e *ImageMissingError) Unwrap() error {
+	return ErrImageNotFound
+}
+
+// ImageMissingErr returns an error handler for an image not available locally
+func ImageMissingErr(img string, platform DockerPlatformConfig) error {
+	return &ImageMissingError{
+		Image:    img,
+		Platform: platform,
+	}
 }
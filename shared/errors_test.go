@@ -0,0 +1,95 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToolError(t *testing.T) {
+	testcases := []struct {
+		err             error
+		wantSentinel    error
+		wantNotFound    bool
+		wantConflict    bool
+		wantUnreachable bool
+		wantMsg         string
+	}{
+		{
+			err:          shared.CreateContainerErr(errdefs.NotFound(errors.New("no such image")), "eth", "compileSol"),
+			wantSentinel: shared.ErrCreateContainer,
+			wantNotFound: true,
+			wantMsg:      "eth.compileSol: unable to create a container: no such image",
+		},
+		{
+			err:          shared.RemoveContainerErr(errdefs.Conflict(errors.New("container is running")), "shared", "RemoveContainer"),
+			wantSentinel: shared.ErrRemoveContainer,
+			wantConflict: true,
+			wantMsg:      "shared.RemoveContainer: unable to remove a container: container is running",
+		},
+		{
+			err:             shared.PullImageError(client.ErrorConnectionFailed("unix:///var/run/docker.sock"), "eth", "NewSolc"),
+			wantSentinel:    shared.ErrPullImage,
+			wantUnreachable: true,
+			wantMsg:         "eth.NewSolc: unable to pull image: Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?",
+		},
+		{
+			err:          shared.ContainerLogErr(errors.New("EOF"), "grpc", "compileProtos"),
+			wantSentinel: shared.ErrContainerLog,
+			wantMsg:      "grpc.compileProtos: unable to instantiate container log: EOF",
+		},
+		{
+			err:          shared.ToolExitErr("abc", 1, "Error: Expected ';'\n", "eth", "compileSol"),
+			wantSentinel: shared.ErrToolFailed,
+			wantMsg:      "eth.compileSol: tool exited with non-zero status: container abc: exit code 1: Error: Expected ';'",
+		},
+	}
+	for i, tc := range testcases {
+		var toolErr *shared.ToolError
+		assert.True(t, errors.As(tc.err, &toolErr), fmt.Sprintf("Case: %d", i))
+		assert.True(t, errors.Is(tc.err, tc.wantSentinel), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.wantSentinel, tc.err))
+		assert.Equal(t, tc.wantNotFound, shared.IsNotFound(tc.err), fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.wantConflict, shared.IsConflict(tc.err), fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.wantUnreachable, shared.IsDaemonUnreachable(tc.err), fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.wantMsg, tc.err.Error(), fmt.Sprintf("Case: %d", i))
+	}
+}
+
+func TestRunnerToolError(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{StartErr: errors.New("exec format error")})
+	_, err := shared.NewRunner(engine).Run(context.Background(), shared.RunSpec{Package: "eth", Func: "compileSol", Image: "tool"})
+
+	var toolErr *shared.ToolError
+	if assert.True(t, errors.As(err, &toolErr)) {
+		assert.Equal(t, shared.OpStartContainer, toolErr.Op)
+		assert.Equal(t, "eth", toolErr.Package)
+		assert.Equal(t, "compileSol", toolErr.Func)
+		assert.Equal(t, "fake-1", toolErr.ContainerID)
+		assert.EqualError(t, toolErr.Err, "exec format error")
+	}
+}
@@ -20,21 +20,86 @@ package shared
 
 import (
 	"context"
+	"errors"
 
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/client"
+	"github.com/docker/docker/api/types/filters"
 )
 
-func RemoveContainer(ctx context.Context, cli *client.Client, containerID string) error {
-	if err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
+// RemoveContainer removes a stopped container. It fails if the container
+// is still running.
+func RemoveContainer(ctx context.Context, cli Engine, containerID string) error {
+	if err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{}); err != nil {
 		return RemoveContainerErr(err, "shared", "RemoveContainer")
 	}
 	return nil
 }
 
-func RemoveContainerForce(ctx context.Context, cli *client.Client, containerID string) error {
+// RemoveContainerForce removes a container, killing it first if it is
+// still running
+func RemoveContainerForce(ctx context.Context, cli Engine, containerID string) error {
 	if err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
 		return RemoveContainerErr(err, "shared", "RemoveContainerForce")
 	}
 	return nil
 }
+
+// RemoveContainerWithVolumes removes a container together with its
+// anonymous volumes. If force is false, it fails if the container is
+// still running.
+func RemoveContainerWithVolumes(ctx context.Context, cli Engine, containerID string, force bool) error {
+	if err := cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: force, RemoveVolumes: true}); err != nil {
+		return RemoveContainerErr(err, "shared", "RemoveContainerWithVolumes")
+	}
+	return nil
+}
+
+// staleStates are the states of containers that are not running and may
+// be removed without disrupting a run
+var staleStates = []string{"created", "exited", "dead"}
+
+// PruneTools removes containers created by this module that are not
+// running, e.g. left behind by crashed jobs, together with their anonymous
+// volumes. If force is true, running containers, e.g. of jobs in flight,
+// are killed and removed too. The filter narrows down the containers
+// further and may be empty. The IDs of the removed containers are returned.
+func PruneTools(ctx context.Context, cli Engine, filter filters.Args, force bool) ([]string, error) {
+	filter = filter.Clone()
+	filter.Add("label", LabelManaged)
+	if !force {
+		for _, state := range staleStates {
+			filter.Add("status", state)
+		}
+	}
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filter,
+	})
+	if err != nil {
+		return nil, ListContainersErr(err, "shared", "PruneTools")
+	}
+
+	var removed []string
+	for _, c := range containers {
+		if err := cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: force, RemoveVolumes: true}); err != nil {
+			return removed, RemoveContainerErr(err, "shared", "PruneTools")
+		}
+		removed = append(removed, c.ID)
+	}
+	return removed, nil
+}
+
+// WaitContainer blocks until the container has stopped and returns
+// its exit status
+func WaitContainer(ctx context.Context, cli Engine, containerID string) (int64, error) {
+	statusCh, errCh := cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return 0, err
+	case status := <-statusCh:
+		if status.Error != nil {
+			return status.StatusCode, errors.New(status.Error.Message)
+		}
+		return status.StatusCode, nil
+	}
+}
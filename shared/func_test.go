@@ -0,0 +1,85 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveContainer(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	resp, err := engine.ContainerCreate(context.Background(), &container.Config{Image: "tool"}, nil, nil, nil, "tool")
+	assert.NoError(t, err)
+	engine.StartContainer(resp.ID)
+
+	err = shared.RemoveContainer(context.Background(), engine, resp.ID)
+	assert.True(t, errors.Is(err, shared.ErrRemoveContainer))
+	assert.Equal(t, []string{resp.ID}, engine.Containers())
+
+	err = shared.RemoveContainerForce(context.Background(), engine, resp.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, engine.Containers())
+}
+
+func TestPruneTools(t *testing.T) {
+	testcases := []struct {
+		state     string
+		force     bool
+		wantPrune bool
+	}{
+		{state: "created", wantPrune: true},
+		{state: "exited", wantPrune: true},
+		{state: "dead", wantPrune: true},
+		{state: "running", wantPrune: false},
+		{state: "running", force: true, wantPrune: true},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		_, err := shared.NewRunner(engine, shared.WithKeepContainer()).Run(context.Background(), shared.RunSpec{Image: "ethereum/solc:0.8.28"})
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		if tc.state == "running" {
+			engine.StartContainer("fake-1")
+		} else {
+			engine.SetContainerState("fake-1", tc.state)
+		}
+
+		// A container not created by this module
+		foreign, err := engine.ContainerCreate(context.Background(), &container.Config{Image: "postgres"}, nil, nil, nil, "db")
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+
+		removed, err := shared.PruneTools(context.Background(), engine, filters.NewArgs(), tc.force)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		if tc.wantPrune {
+			assert.Equal(t, []string{"fake-1"}, removed, fmt.Sprintf("Case: %d", i))
+			assert.Equal(t, []string{foreign.ID}, engine.Containers(), fmt.Sprintf("Case: %d", i))
+		} else {
+			assert.Empty(t, removed, fmt.Sprintf("Case: %d", i))
+			assert.Equal(t, []string{"fake-1", foreign.ID}, engine.Containers(), fmt.Sprintf("Case: %d", i))
+		}
+	}
+}
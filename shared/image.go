@@ -0,0 +1,137 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+)
+
+// PullPolicy represents when a tool image is pulled
+type PullPolicy int
+
+const (
+	// PullIfNotPresent pulls the image only if it is not available locally
+	// for the tool platform
+	PullIfNotPresent PullPolicy = iota
+	// PullAlways pulls the image every time it is prepared, i.e. once per
+	// tool client
+	PullAlways
+	// PullNever requires the image to be available locally
+	PullNever
+)
+
+func (p PullPolicy) String() string {
+	switch p {
+	case PullIfNotPresent:
+		return "IfNotPresent"
+	case PullAlways:
+		return "Always"
+	case PullNever:
+		return "Never"
+	default:
+		return "Unknown"
+	}
+}
+
+// EnsureImage makes the image available locally for the given platform as
+// per policy. Under PullNever, a missing image is reported as an
+// *ImageMissingError, which matches ErrImageNotFound. If opts include
+// WithLockfile, an image that does not match its pinned digest is reported
+// as a *DigestMismatchError. Failing pulls are retried as configured
+// WithPullRetry.
+func EnsureImage(ctx context.Context, cli Engine, img string, platform DockerPlatformConfig, policy PullPolicy, opts ...Option) error {
+	return ensureImage(ctx, cli, img, platform, policy, NewConfig(opts...))
+}
+
+func ensureImage(ctx context.Context, cli Engine, img string, platform DockerPlatformConfig, policy PullPolicy, cfg Config) error {
+	if err := fetchImage(ctx, cli, img, platform, policy, cfg); err != nil {
+		return err
+	}
+	return cfg.Lockfile.verifyDigest(ctx, cli, img)
+}
+
+func fetchImage(ctx context.Context, cli Engine, img string, platform DockerPlatformConfig, policy PullPolicy, cfg Config) error {
+	if policy == PullAlways {
+		return pullWithRetry(ctx, cli, img, platform, cfg)
+	}
+
+	present, err := isImagePresent(ctx, cli, img, platform)
+	if err != nil {
+		return err
+	}
+	if present {
+		return nil
+	}
+	if policy == PullNever {
+		return ImageMissingErr(img, platform)
+	}
+	return pullWithRetry(ctx, cli, img, platform, cfg)
+}
+
+// PullImage pulls an image for the given platform. Progress is reported
+// to the callback set WithPullProgress, and a summary line is routed as
+// per opts, defaulting to os.Stdout.
+func PullImage(ctx context.Context, cli Engine, img string, platform DockerPlatformConfig, opts ...Option) error {
+	return pullImage(ctx, cli, img, platform, NewConfig(opts...))
+}
+
+func pullImage(ctx context.Context, cli Engine, img string, platform DockerPlatformConfig, cfg Config) error {
+	auth, err := cfg.registryAuth(ctx, img)
+	if err != nil {
+		return err
+	}
+	reader, err := cli.ImagePull(ctx, img, image.PullOptions{
+		Platform:     platform.String(),
+		RegistryAuth: auth,
+	})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	sink, flush := cfg.stdout("image", img)
+	defer flush()
+	return cfg.decodePull(reader, img, sink)
+}
+
+func isImagePresent(ctx context.Context, cli Engine, img string, platform DockerPlatformConfig) (bool, error) {
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, img)
+	if errdefs.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if platform.OS != "" && inspect.Os != platform.OS {
+		return false, nil
+	}
+	if platform.Arch != "" && inspect.Architecture != platform.Arch {
+		return false, nil
+	}
+	if platform.Variant != "" && inspect.Variant != platform.Variant {
+		// Podman reports no variant for an architecture's default one
+		if inspect.Variant != "" || !defaultVariant(platform.Arch, platform.Variant) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
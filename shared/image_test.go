@@ -0,0 +1,81 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureImage(t *testing.T) {
+	const img = "ethereum/solc:0.8.28"
+
+	testcases := []struct {
+		local     *shared.DockerPlatformConfig
+		policy    shared.PullPolicy
+		wantPulls int
+		wantErr   error
+	}{
+		{
+			policy:    shared.PullIfNotPresent,
+			wantPulls: 1,
+		},
+		{
+			local:     &shared.DockerPlatformConfig{OS: "linux", Arch: "amd64"},
+			policy:    shared.PullIfNotPresent,
+			wantPulls: 0,
+		},
+		{
+			local:     &shared.DockerPlatformConfig{OS: "linux", Arch: "arm64"},
+			policy:    shared.PullIfNotPresent,
+			wantPulls: 1,
+		},
+		{
+			local:     &shared.DockerPlatformConfig{OS: "linux", Arch: "amd64"},
+			policy:    shared.PullAlways,
+			wantPulls: 1,
+		},
+		{
+			local:     &shared.DockerPlatformConfig{OS: "linux", Arch: "amd64"},
+			policy:    shared.PullNever,
+			wantPulls: 0,
+		},
+		{
+			policy:    shared.PullNever,
+			wantPulls: 0,
+			wantErr:   shared.ErrImageNotFound,
+		},
+	}
+
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		if tc.local != nil {
+			engine.AddImage(img, *tc.local)
+		}
+		err := shared.EnsureImage(context.Background(), engine, img, shared.PlatformLinuxAMD64(), tc.policy)
+		assert.True(t, errors.Is(err, tc.wantErr), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.wantErr, err))
+		assert.Len(t, engine.Pulls(), tc.wantPulls, fmt.Sprintf("Case: %d Policy: %v", i, tc.policy))
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Labels attached to every container created by this module
+const (
+	labelPrefix = "io.github.paulwizviz.narwhal."
+
+	// LabelManaged marks a container as created by this module
+	LabelManaged = labelPrefix + "managed"
+	// LabelTool is the name of the tool run in the container, e.g. solc
+	LabelTool = labelPrefix + "tool"
+	// LabelVersion is the version of this module that created the container
+	LabelVersion = labelPrefix + "version"
+	// LabelInputHash is the SHA-256 of the image, command and entry files of
+	// a run
+	LabelInputHash = labelPrefix + "input-hash"
+	// LabelRunID is the caller supplied run ID, see WithRunID
+	LabelRunID = labelPrefix + "run-id"
+)
+
+const modulePath = "github.com/paulwizviz/narwhal"
+
+var (
+	versionOnce sync.Once
+	version     = "devel"
+)
+
+// Version returns the version of this module as recorded in the build
+// information of the calling binary, or "devel" if it is not available
+func Version() string {
+	versionOnce.Do(func() {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if info.Main.Path == modulePath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+			return
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+				return
+			}
+		}
+	})
+	return version
+}
+
+// ToolContainer represents a container created by this module
+type ToolContainer struct {
+	ID        string
+	Name      string
+	Image     string
+	State     string
+	Tool      string
+	Version   string
+	InputHash string
+	RunID     string
+	Labels    map[string]string
+}
+
+// ListToolContainers lists all containers created by this module, running
+// or not. The filter narrows down the containers further and may be empty,
+// e.g. filters.NewArgs(filters.Arg("label", LabelTool+"=solc")).
+func ListToolContainers(ctx context.Context, cli Engine, filter filters.Args) ([]ToolContainer, error) {
+	filter = filter.Clone()
+	filter.Add("label", LabelManaged)
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filter,
+	})
+	if err != nil {
+		return nil, ListContainersErr(err, "shared", "ListToolContainers")
+	}
+
+	var tools []ToolContainer
+	for _, c := range containers {
+		var name string
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		tools = append(tools, ToolContainer{
+			ID:        c.ID,
+			Name:      name,
+			Image:     c.Image,
+			State:     c.State,
+			Tool:      c.Labels[LabelTool],
+			Version:   c.Labels[LabelVersion],
+			InputHash: c.Labels[LabelInputHash],
+			RunID:     c.Labels[LabelRunID],
+			Labels:    c.Labels,
+		})
+	}
+	return tools, nil
+}
+
+// labels returns the provenance labels of a run
+func labels(spec RunSpec, runID string) (map[string]string, error) {
+	hash, err := inputHash(spec)
+	if err != nil {
+		return nil, err
+	}
+	l := map[string]string{
+		LabelManaged:   "true",
+		LabelVersion:   Version(),
+		LabelInputHash: hash,
+	}
+	if spec.Tool != "" {
+		l[LabelTool] = spec.Tool
+	}
+	if runID != "" {
+		l[LabelRunID] = runID
+	}
+	return l, nil
+}
+
+// inputHash digests the image, command and the content of the entry files
+// the command refers to, i.e. arguments naming a file in a read-only mount
+// either by absolute path or relative to the working directory. Other files
+// of the mounts, e.g. a project's libraries, are not read.
+func inputHash(spec RunSpec) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, spec.Image)
+	for _, arg := range spec.Cmd {
+		io.WriteString(h, "\x00"+arg)
+	}
+	for _, arg := range spec.Cmd {
+		name, ok := inputFile(spec, arg)
+		if !ok {
+			continue
+		}
+		f, err := os.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		info, err := f.Stat()
+		if err == nil && info.Mode().IsRegular() {
+			io.WriteString(h, "\x00"+arg+"\x00")
+			_, err = io.Copy(h, f)
+		}
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// inputFile returns the host path of a command argument naming a path in
+// a read-only mount
+func inputFile(spec RunSpec, arg string) (string, bool) {
+	target := arg
+	if !path.IsAbs(target) {
+		if spec.WorkDir == "" || strings.HasPrefix(arg, "-") {
+			return "", false
+		}
+		target = path.Join(spec.WorkDir, arg)
+	}
+	for _, m := range spec.Mounts {
+		if !m.ReadOnly {
+			continue
+		}
+		if target == m.Target {
+			return m.Source, true
+		}
+		if rel, ok := strings.CutPrefix(target, strings.TrimSuffix(m.Target, "/")+"/"); ok {
+			return filepath.Join(m.Source, filepath.FromSlash(rel)), true
+		}
+	}
+	return "", false
+}
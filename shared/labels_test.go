@@ -0,0 +1,101 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/filters"
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListToolContainers(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "hello.sol")
+	assert.NoError(t, os.WriteFile(src, []byte("contract A {}"), 0644))
+
+	engine := narwhaltest.NewEngine()
+	runner := shared.NewRunner(engine, shared.WithKeepContainer(), shared.WithRunID("job-42"))
+	spec := shared.RunSpec{
+		Tool:   "solc",
+		Name:   "first",
+		Image:  "ethereum/solc:0.8.28",
+		Cmd:    []string{"--abi", "/opt/solidity/hello.sol"},
+		Mounts: []shared.Mount{{Source: src, Target: "/opt/solidity/hello.sol", ReadOnly: true}},
+	}
+	_, err := runner.Run(context.Background(), spec)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.WriteFile(src, []byte("contract B {}"), 0644))
+	spec.Name = "second"
+	_, err = runner.Run(context.Background(), spec)
+	assert.NoError(t, err)
+
+	_, err = runner.Run(context.Background(), shared.RunSpec{Tool: "protoc", Image: "narwhal/protoc:current"})
+	assert.NoError(t, err)
+
+	tools, err := shared.ListToolContainers(context.Background(), engine, filters.NewArgs(filters.Arg("label", shared.LabelTool+"=solc")))
+	assert.NoError(t, err)
+	if assert.Len(t, tools, 2) {
+		assert.Equal(t, "first", tools[0].Name)
+		assert.Equal(t, "solc", tools[0].Tool)
+		assert.Equal(t, "job-42", tools[0].RunID)
+		assert.Equal(t, shared.Version(), tools[0].Version)
+		assert.Len(t, tools[0].InputHash, 64)
+		assert.NotEqual(t, tools[0].InputHash, tools[1].InputHash)
+	}
+}
+
+func TestInputHash(t *testing.T) {
+	root := t.TempDir()
+	entry := filepath.Join(root, "contracts", "Token.sol")
+	lib := filepath.Join(root, "lib", "Math.sol")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(entry), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(lib), 0755))
+	assert.NoError(t, os.WriteFile(entry, []byte("contract Token {}"), 0644))
+	assert.NoError(t, os.WriteFile(lib, []byte("library Math {}"), 0644))
+
+	engine := narwhaltest.NewEngine()
+	runner := shared.NewRunner(engine)
+	spec := shared.RunSpec{
+		Image:   "ethereum/solc:0.8.28",
+		Cmd:     []string{"--abi", "contracts/Token.sol", "--base-path", "/opt/project"},
+		WorkDir: "/opt/project",
+		Mounts:  []shared.Mount{{Source: root, Target: "/opt/project", ReadOnly: true}},
+	}
+	hash := func() string {
+		_, err := runner.Run(context.Background(), spec)
+		assert.NoError(t, err)
+		creates := engine.Creates()
+		return creates[len(creates)-1].Config.Labels[shared.LabelInputHash]
+	}
+
+	first := hash()
+	// Files the command does not refer to are not read
+	assert.NoError(t, os.WriteFile(lib, []byte("library Math { }"), 0644))
+	assert.Equal(t, first, hash())
+	// Entry files are
+	assert.NoError(t, os.WriteFile(entry, []byte("contract Token { }"), 0644))
+	assert.NotEqual(t, first, hash())
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// sink returns a writer that routes output to the configured writer and
+// logger, falling back to def when neither is set. The returned flush
+// function logs any trailing partial line.
+func (c Config) sink(w io.Writer, def io.Writer, level slog.Level, attrs ...any) (io.Writer, func()) {
+	var writers []io.Writer
+	if w != nil {
+		writers = append(writers, w)
+	}
+	var lw *logWriter
+	if c.Logger != nil {
+		lw = &logWriter{
+			logger: c.Logger,
+			level:  level,
+			attrs:  attrs,
+		}
+		writers = append(writers, lw)
+	}
+	if len(writers) == 0 {
+		writers = append(writers, def)
+	}
+	return io.MultiWriter(writers...), func() {
+		if lw != nil {
+			lw.flush()
+		}
+	}
+}
+
+func (c Config) stdout(attrs ...any) (io.Writer, func()) {
+	return c.sink(c.Stdout, os.Stdout, slog.LevelInfo, append(attrs, "stream", "stdout")...)
+}
+
+func (c Config) stderr(attrs ...any) (io.Writer, func()) {
+	return c.sink(c.Stderr, os.Stderr, slog.LevelWarn, append(attrs, "stream", "stderr")...)
+}
+
+// logWriter emits a log record for every complete line written to it
+type logWriter struct {
+	logger *slog.Logger
+	level  slog.Level
+	attrs  []any
+	buf    []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *logWriter) flush() {
+	if len(w.buf) > 0 {
+		w.log(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *logWriter) log(line string) {
+	w.logger.Log(context.Background(), w.level, "tool output", append(w.attrs[:len(w.attrs):len(w.attrs)], "line", line)...)
+}
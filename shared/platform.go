@@ -18,6 +18,15 @@
 
 package shared
 
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
 const (
 	osDarwin    = "darwin"
 	osDragonFly = "dragonfly"
@@ -71,11 +80,142 @@ const (
 //   {os: "windows", arch: "386"}
 //   {os: "windows", arch: "amd64"}
 
+// supportedPlatforms is the matrix of OS and architectures above
+var supportedPlatforms = map[string][]string{
+	osDarwin:    {arch386, archAMD64, archARM, archARM64},
+	osDragonFly: {archAMD64},
+	osFreeBSD:   {arch386, archAMD64, archARM},
+	osLinux:     {arch386, archAMD64, archARM, archARM64, archPPC64le, archMIPS64, archMIPS64le, archS390X},
+	osNetBSD:    {arch386, archAMD64, archARM},
+	osOpenBSD:   {arch386, archAMD64, archARM},
+	osPlan9:     {arch386, archAMD64},
+	osSolaris:   {archAMD64},
+	osWindows:   {arch386, archAMD64},
+}
+
+// supportedVariants lists the variants of architectures that have them
+var supportedVariants = map[string][]string{
+	archARM:   {"v5", "v6", "v7", "v8"},
+	archARM64: {"v8"},
+}
+
 // DockerPlatformConfig represents configuration of Docker
 // platform
 type DockerPlatformConfig struct {
-	OS   string
-	Arch string
+	OS      string
+	Arch    string
+	Variant string
+}
+
+// ParsePlatform parses a platform string of the form os/arch[/variant],
+// e.g. linux/arm64/v8, and validates it against the supported platforms
+func ParsePlatform(platform string) (DockerPlatformConfig, error) {
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(platform)), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return DockerPlatformConfig{}, fmt.Errorf("%w: %s", ErrInvalidPlatform, platform)
+	}
+	p := DockerPlatformConfig{
+		OS:   parts[0],
+		Arch: parts[1],
+	}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	if err := p.Validate(); err != nil {
+		return DockerPlatformConfig{}, err
+	}
+	return p, nil
+}
+
+// PlatformFromOCI converts an OCI platform and validates it against the
+// supported platforms
+func PlatformFromOCI(platform v1.Platform) (DockerPlatformConfig, error) {
+	p := DockerPlatformConfig{
+		OS:      platform.OS,
+		Arch:    platform.Architecture,
+		Variant: platform.Variant,
+	}
+	if err := p.Validate(); err != nil {
+		return DockerPlatformConfig{}, err
+	}
+	return p, nil
+}
+
+// HostPlatform returns the platform of the engine's daemon, which is the
+// platform its containers run natively
+func HostPlatform(ctx context.Context, cli Engine) (DockerPlatformConfig, error) {
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return DockerPlatformConfig{}, err
+	}
+	arch, variant := normaliseArch(info.Architecture)
+	p := DockerPlatformConfig{
+		OS:      strings.ToLower(info.OSType),
+		Arch:    arch,
+		Variant: variant,
+	}
+	if err := p.Validate(); err != nil {
+		return DockerPlatformConfig{}, err
+	}
+	return p, nil
+}
+
+// normaliseArch maps architectures reported by the daemon, which follow
+// uname, to their Go equivalent
+func normaliseArch(arch string) (string, string) {
+	switch strings.ToLower(arch) {
+	case "x86_64", "x86-64", "amd64":
+		return archAMD64, ""
+	case "aarch64", "arm64":
+		return archARM64, ""
+	case "armhf", "armv7l", "armv7":
+		return archARM, "v7"
+	case "armel", "armv6l", "armv6":
+		return archARM, "v6"
+	case "armv5tel", "armv5l", "armv5":
+		return archARM, "v5"
+	case "i386", "i486", "i586", "i686", "x86":
+		return arch386, ""
+	default:
+		return strings.ToLower(arch), ""
+	}
+}
+
+// String returns the platform in the form os/arch[/variant] as accepted
+// by the Docker API
+func (p DockerPlatformConfig) String() string {
+	if p.OS == "" && p.Arch == "" {
+		return ""
+	}
+	if p.Variant != "" {
+		return fmt.Sprintf("%s/%s/%s", p.OS, p.Arch, p.Variant)
+	}
+	return fmt.Sprintf("%s/%s", p.OS, p.Arch)
+}
+
+// Validate returns ErrInvalidPlatform if the platform is not supported
+func (p DockerPlatformConfig) Validate() error {
+	archs, ok := supportedPlatforms[p.OS]
+	if !ok || !slices.Contains(archs, p.Arch) {
+		return fmt.Errorf("%w: %s", ErrInvalidPlatform, p)
+	}
+	if p.Variant != "" && !slices.Contains(supportedVariants[p.Arch], p.Variant) {
+		return fmt.Errorf("%w: %s", ErrInvalidPlatform, p)
+	}
+	return nil
+}
+
+// OCI returns the platform as an OCI platform, or nil if the platform
+// is not set
+func (p DockerPlatformConfig) OCI() *v1.Platform {
+	if p.OS == "" && p.Arch == "" {
+		return nil
+	}
+	return &v1.Platform{
+		OS:           p.OS,
+		Architecture: p.Arch,
+		Variant:      p.Variant,
+	}
 }
 
 // PlatformDarwin386 returns platform configured for
@@ -87,6 +227,15 @@ func PlatformDarwin386() DockerPlatformConfig {
 	}
 }
 
+// PlatformDarwinAMD64 returns platform configured for
+// Darwin and AMD64
+func PlatformDarwinAMD64() DockerPlatformConfig {
+	return DockerPlatformConfig{
+		OS:   osDarwin,
+		Arch: archAMD64,
+	}
+}
+
 // PlatformDarwinARM returns platform configured for
 // Darwin and ARM
 func PlatformDarwinARM() DockerPlatformConfig {
@@ -106,10 +255,10 @@ func PlatformDarwinARM64() DockerPlatformConfig {
 }
 
 // PlatformDragonFlyAMD64 returns platform configured for
-// DragonFly and ARM64
+// DragonFly and AMD64
 func PlatformDragonFlyAMD64() DockerPlatformConfig {
 	return DockerPlatformConfig{
-		OS:   osDarwin,
+		OS:   osDragonFly,
 		Arch: archAMD64,
 	}
 }
@@ -141,7 +290,7 @@ func PlatformFreeBSDARM() DockerPlatformConfig {
 	}
 }
 
-// PlatformDarwinArm64 returns platform configured for
+// PlatformLinux386 returns platform configured for
 // Linux and 386
 func PlatformLinux386() DockerPlatformConfig {
 	return DockerPlatformConfig{
@@ -168,21 +317,46 @@ func PlatformLinuxARM() DockerPlatformConfig {
 	}
 }
 
-// PlatformLinuxARM returns platform configured for
-// Linux and ARM
-func PlaformLinuxARM64() DockerPlatformConfig {
+// PlatformLinuxARM64 returns platform configured for
+// Linux and ARM64
+func PlatformLinuxARM64() DockerPlatformConfig {
 	return DockerPlatformConfig{
 		OS:   osLinux,
 		Arch: archARM64,
 	}
 }
 
+// PlaformLinuxARM64 returns platform configured for
+// Linux and ARM64
+//
+// Deprecated: use PlatformLinuxARM64
+func PlaformLinuxARM64() DockerPlatformConfig {
+	return PlatformLinuxARM64()
+}
+
+// PlatformLinuxMIPS64 returns platform configured for
+// Linux and MIPS64
+func PlatformLinuxMIPS64() DockerPlatformConfig {
+	return DockerPlatformConfig{
+		OS:   osLinux,
+		Arch: archMIPS64,
+	}
+}
+
 // PlaformLinuxMIPS64 returns platform configured for
 // Linux and MIPS64
+//
+// Deprecated: use PlatformLinuxMIPS64
 func PlaformLinuxMIPS64() DockerPlatformConfig {
+	return PlatformLinuxMIPS64()
+}
+
+// PlatformLinuxMIPS64le returns platform configured for
+// Linux and MIPS64le
+func PlatformLinuxMIPS64le() DockerPlatformConfig {
 	return DockerPlatformConfig{
 		OS:   osLinux,
-		Arch: archMIPS64,
+		Arch: archMIPS64le,
 	}
 }
 
@@ -204,15 +378,23 @@ func PlatformLinuxS390X() DockerPlatformConfig {
 	}
 }
 
-// PlatformNetBSDS386 returns platform configured for
+// PlatformNetBSD386 returns platform configured for
 // NetBSD and 386
-func PlatformNetBSDS386() DockerPlatformConfig {
+func PlatformNetBSD386() DockerPlatformConfig {
 	return DockerPlatformConfig{
 		OS:   osNetBSD,
 		Arch: arch386,
 	}
 }
 
+// PlatformNetBSDS386 returns platform configured for
+// NetBSD and 386
+//
+// Deprecated: use PlatformNetBSD386
+func PlatformNetBSDS386() DockerPlatformConfig {
+	return PlatformNetBSD386()
+}
+
 // PlatformNetBSDAMD64 returns platform configured for
 // NetBSD and AMD64
 func PlatformNetBSDAMD64() DockerPlatformConfig {
@@ -232,11 +414,11 @@ func PlatformNetBSDARM() DockerPlatformConfig {
 }
 
 // PlatformOpenBSD386 returns platform configured for
-// OpenBSD and ARM
+// OpenBSD and 386
 func PlatformOpenBSD386() DockerPlatformConfig {
 	return DockerPlatformConfig{
 		OS:   osOpenBSD,
-		Arch: archARM,
+		Arch: arch386,
 	}
 }
 
@@ -259,7 +441,7 @@ func PlatformOpenBSDARM() DockerPlatformConfig {
 }
 
 // PlatformPlan9386 returns platform configured for
-// Plan9 and 380
+// Plan9 and 386
 func PlatformPlan9386() DockerPlatformConfig {
 	return DockerPlatformConfig{
 		OS:   osPlan9,
@@ -280,25 +462,41 @@ func PlatformPlan9AMD64() DockerPlatformConfig {
 // Solaris and AMD64
 func PlatformSolarisAMD64() DockerPlatformConfig {
 	return DockerPlatformConfig{
-		OS:   osPlan9,
+		OS:   osSolaris,
 		Arch: archAMD64,
 	}
 }
 
-// Windows386 returns platform configured for
+// PlatformWindows386 returns platform configured for
 // Windows and 386
-func Windows386() DockerPlatformConfig {
+func PlatformWindows386() DockerPlatformConfig {
 	return DockerPlatformConfig{
-		OS:   osPlan9,
+		OS:   osWindows,
 		Arch: arch386,
 	}
 }
 
-// WindowsAMD64 returns platform configured for
+// PlatformWindowsAMD64 returns platform configured for
 // Windows and AMD64
-func WindowsAMD64() DockerPlatformConfig {
+func PlatformWindowsAMD64() DockerPlatformConfig {
 	return DockerPlatformConfig{
-		OS:   osPlan9,
-		Arch: arch386,
+		OS:   osWindows,
+		Arch: archAMD64,
 	}
 }
+
+// Windows386 returns platform configured for
+// Windows and 386
+//
+// Deprecated: use PlatformWindows386
+func Windows386() DockerPlatformConfig {
+	return PlatformWindows386()
+}
+
+// WindowsAMD64 returns platform configured for
+// Windows and AMD64
+//
+// Deprecated: use PlatformWindowsAMD64
+func WindowsAMD64() DockerPlatformConfig {
+	return PlatformWindowsAMD64()
+}
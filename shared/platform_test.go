@@ -0,0 +1,158 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/docker/docker/api/types/system"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePlatform(t *testing.T) {
+	testcases := []struct {
+		input   string
+		want    shared.DockerPlatformConfig
+		wantErr error
+	}{
+		{
+			input: "linux/amd64",
+			want:  shared.PlatformLinuxAMD64(),
+		},
+		{
+			input: "linux/arm64/v8",
+			want:  shared.DockerPlatformConfig{OS: "linux", Arch: "arm64", Variant: "v8"},
+		},
+		{
+			input: "Linux/ARM/v7",
+			want:  shared.DockerPlatformConfig{OS: "linux", Arch: "arm", Variant: "v7"},
+		},
+		{
+			input: "windows/amd64",
+			want:  shared.PlatformWindowsAMD64(),
+		},
+		{
+			input:   "linux",
+			wantErr: shared.ErrInvalidPlatform,
+		},
+		{
+			input:   "solaris/arm64",
+			wantErr: shared.ErrInvalidPlatform,
+		},
+		{
+			input:   "linux/amd64/v7",
+			wantErr: shared.ErrInvalidPlatform,
+		},
+		{
+			input:   "linux/arm/v7/extra",
+			wantErr: shared.ErrInvalidPlatform,
+		},
+	}
+	for i, tc := range testcases {
+		got, err := shared.ParsePlatform(tc.input)
+		assert.True(t, errors.Is(err, tc.wantErr), fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.wantErr, err))
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
+
+func TestPlatformString(t *testing.T) {
+	testcases := []struct {
+		input shared.DockerPlatformConfig
+		want  string
+	}{
+		{
+			input: shared.PlatformLinuxAMD64(),
+			want:  "linux/amd64",
+		},
+		{
+			input: shared.DockerPlatformConfig{OS: "linux", Arch: "arm", Variant: "v6"},
+			want:  "linux/arm/v6",
+		},
+		{
+			input: shared.DockerPlatformConfig{},
+			want:  "",
+		},
+	}
+	for i, tc := range testcases {
+		got := tc.input.String()
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
+
+func TestPlatformConstructors(t *testing.T) {
+	testcases := []struct {
+		input shared.DockerPlatformConfig
+		want  string
+	}{
+		{input: shared.PlatformDragonFlyAMD64(), want: "dragonfly/amd64"},
+		{input: shared.PlatformOpenBSD386(), want: "openbsd/386"},
+		{input: shared.PlatformSolarisAMD64(), want: "solaris/amd64"},
+		{input: shared.PlatformWindows386(), want: "windows/386"},
+		{input: shared.PlatformWindowsAMD64(), want: "windows/amd64"},
+	}
+	for i, tc := range testcases {
+		assert.NoError(t, tc.input.Validate(), fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.want, tc.input.String(), fmt.Sprintf("Case: %d", i))
+	}
+}
+
+func TestPlatformOCI(t *testing.T) {
+	p := shared.DockerPlatformConfig{OS: "linux", Arch: "arm64", Variant: "v8"}
+	oci := p.OCI()
+	assert.Equal(t, &v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, oci)
+
+	got, err := shared.PlatformFromOCI(*oci)
+	assert.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	assert.Nil(t, shared.DockerPlatformConfig{}.OCI())
+}
+
+func TestHostPlatform(t *testing.T) {
+	testcases := []struct {
+		info system.Info
+		want shared.DockerPlatformConfig
+	}{
+		{
+			info: system.Info{OSType: "linux", Architecture: "x86_64"},
+			want: shared.PlatformLinuxAMD64(),
+		},
+		{
+			info: system.Info{OSType: "linux", Architecture: "aarch64"},
+			want: shared.PlatformLinuxARM64(),
+		},
+		{
+			info: system.Info{OSType: "linux", Architecture: "armv7l"},
+			want: shared.DockerPlatformConfig{OS: "linux", Arch: "arm", Variant: "v7"},
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		engine.SystemInfo = tc.info
+		got, err := shared.HostPlatform(context.Background(), engine)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
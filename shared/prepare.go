@@ -0,0 +1,146 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/errdefs"
+)
+
+const (
+	// DefaultPullAttempts is the number of times a failing pull is tried
+	DefaultPullAttempts = 3
+	// DefaultPullBackoff is the wait before the first retry of a pull. It
+	// doubles with every retry.
+	DefaultPullBackoff = time.Second
+)
+
+// ToolImage represents the image of a tool, made available locally as per
+// the configured pull policy on first use rather than when the tool is
+// instantiated
+type ToolImage struct {
+	cfg Config
+
+	mu      sync.Mutex
+	ready   bool
+	pending *prepareCall
+}
+
+// prepareCall represents a Prepare in progress, which concurrent callers
+// wait for
+type prepareCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewToolImage instantiate a ToolImage for cfg.Image and cfg.Platform,
+// pulled from cfg.Engine
+func NewToolImage(cfg Config) *ToolImage {
+	return &ToolImage{cfg: cfg}
+}
+
+// Prepare makes the image available locally, unless an earlier call has
+// done so already. It is bounded by ctx, including while it waits for a
+// concurrent call to finish, and a failed attempt leaves the next call to
+// try again.
+func (t *ToolImage) Prepare(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		if t.ready {
+			t.mu.Unlock()
+			return nil
+		}
+		if call := t.pending; call != nil {
+			t.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-call.done:
+			}
+			// The caller that pulled gave up, which says nothing of this
+			// one, so try again
+			if isContextErr(call.err) {
+				continue
+			}
+			return call.err
+		}
+		call := &prepareCall{done: make(chan struct{})}
+		t.pending = call
+		t.mu.Unlock()
+
+		call.err = ensureImage(ctx, t.cfg.Engine, t.cfg.Image, t.cfg.Platform, t.cfg.PullPolicy, t.cfg)
+
+		t.mu.Lock()
+		t.ready = call.err == nil
+		t.pending = nil
+		t.mu.Unlock()
+		close(call.done)
+		return call.err
+	}
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// pullWithRetry pulls an image, retrying transient failures with
+// exponential backoff as configured WithPullRetry
+func pullWithRetry(ctx context.Context, cli Engine, img string, platform DockerPlatformConfig, cfg Config) error {
+	attempts, backoff := cfg.PullAttempts, cfg.PullBackoff
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		err = pullImage(ctx, cli, img, platform, cfg)
+		if err == nil || !isRetryable(ctx, err) {
+			return err
+		}
+	}
+	return err
+}
+
+// isRetryable reports whether a pull failure may be transient. Errors
+// that another attempt cannot fix, such as an unknown image or rejected
+// credentials, are not retried.
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch {
+	case errdefs.IsNotFound(err),
+		errdefs.IsUnauthorized(err),
+		errdefs.IsForbidden(err),
+		errdefs.IsInvalidParameter(err):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/errdefs"
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToolImagePrepare(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	img := shared.NewToolImage(shared.NewConfig(
+		shared.WithClient(engine),
+		shared.WithImage("ethereum/solc:0.8.28"),
+		shared.WithPlatform(shared.PlatformLinuxAMD64()),
+	))
+	assert.Len(t, engine.Pulls(), 0)
+
+	assert.NoError(t, img.Prepare(context.Background()))
+	assert.NoError(t, img.Prepare(context.Background()))
+	assert.Len(t, engine.Pulls(), 1)
+}
+
+// slowPullEngine holds pulls until release is closed
+type slowPullEngine struct {
+	*narwhaltest.Engine
+	release chan struct{}
+}
+
+func (e slowPullEngine) ImagePull(ctx context.Context, ref string, options image.PullOptions) (io.ReadCloser, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-e.release:
+	}
+	return e.Engine.ImagePull(ctx, ref, options)
+}
+
+func TestToolImagePrepareConcurrent(t *testing.T) {
+	engine := slowPullEngine{Engine: narwhaltest.NewEngine(), release: make(chan struct{})}
+	img := shared.NewToolImage(shared.NewConfig(
+		shared.WithClient(engine),
+		shared.WithImage("ethereum/solc:0.8.28"),
+		shared.WithPlatform(shared.PlatformLinuxAMD64()),
+		shared.WithStdout(io.Discard),
+	))
+
+	first := make(chan error, 1)
+	go func() { first <- img.Prepare(context.Background()) }()
+	// The first caller has started once the engine sees it
+	for len(engine.Calls()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// A caller waiting behind the pull in flight is bounded by its own ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.ErrorIs(t, img.Prepare(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	close(engine.release)
+	assert.NoError(t, <-first)
+	assert.NoError(t, img.Prepare(context.Background()))
+	assert.Len(t, engine.Pulls(), 1)
+}
+
+func TestToolImagePrepareRetry(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	engine.PullErr = errdefs.NotFound(errors.New("manifest unknown"))
+	img := shared.NewToolImage(shared.NewConfig(
+		shared.WithClient(engine),
+		shared.WithImage("ethereum/solc:0.8.28"),
+		shared.WithPlatform(shared.PlatformLinuxAMD64()),
+	))
+
+	assert.Error(t, img.Prepare(context.Background()))
+	// Failures are not cached
+	engine.PullErr = nil
+	assert.NoError(t, img.Prepare(context.Background()))
+	assert.Len(t, engine.Pulls(), 2)
+}
+
+func TestPullRetry(t *testing.T) {
+	testcases := []struct {
+		pullErr   error
+		wantPulls int
+	}{
+		{pullErr: errors.New("connection reset by peer"), wantPulls: 3},
+		{pullErr: errdefs.NotFound(errors.New("manifest unknown")), wantPulls: 1},
+		{pullErr: errdefs.Unauthorized(errors.New("authentication required")), wantPulls: 1},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		engine.PullErr = tc.pullErr
+		err := shared.EnsureImage(context.Background(), engine, "ethereum/solc:0.8.28", shared.PlatformLinuxAMD64(), shared.PullAlways,
+			shared.WithPullRetry(3, time.Millisecond))
+		assert.ErrorIs(t, err, tc.pullErr, fmt.Sprintf("Case: %d", i))
+		assert.Len(t, engine.Pulls(), tc.wantPulls, fmt.Sprintf("Case: %d", i))
+	}
+}
+
+func TestPullRetryCancel(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	engine.PullErr = errors.New("connection reset by peer")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := shared.EnsureImage(ctx, engine, "ethereum/solc:0.8.28", shared.PlatformLinuxAMD64(), shared.PullAlways,
+		shared.WithPullRetry(5, time.Minute))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Len(t, engine.Pulls(), 1)
+}
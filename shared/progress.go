@@ -0,0 +1,119 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// PullEvent represents a progress message of an image pull. Layer events
+// carry the layer's ID; events about the image as a whole, e.g. its
+// digest, do not.
+type PullEvent struct {
+	Image string
+	// ID is the layer ID, e.g. 3f4ca61aafcd
+	ID string
+	// Status is e.g. Downloading, Extracting or Pull complete
+	Status string
+	// Current and Total are the bytes transferred for the layer so far
+	// and in all. Total is 0 when unknown.
+	Current int64
+	Total   int64
+}
+
+// pullSummary accumulates the outcome of a pull from its events
+type pullSummary struct {
+	image  string
+	digest string
+	layers map[string]int64
+}
+
+func (s *pullSummary) add(e PullEvent) {
+	if strings.HasPrefix(e.Status, "Pulling from ") {
+		// The ID of this message is the tag being pulled
+		return
+	}
+	if e.ID == "" {
+		if digest, ok := strings.CutPrefix(e.Status, "Digest: "); ok {
+			s.digest = digest
+		}
+		return
+	}
+	if _, ok := s.layers[e.ID]; !ok {
+		s.layers[e.ID] = 0
+	}
+	if e.Status == "Downloading" && e.Total > s.layers[e.ID] {
+		s.layers[e.ID] = e.Total
+	}
+}
+
+func (s *pullSummary) String() string {
+	var size int64
+	for _, total := range s.layers {
+		size += total
+	}
+	line := "pulled " + s.image
+	if s.digest != "" {
+		line += " " + s.digest
+	}
+	return fmt.Sprintf("%s: %d layers, %.1f MB downloaded", line, len(s.layers), float64(size)/1e6)
+}
+
+// decodePull decodes the message stream of an image pull, passing each
+// message to the configured progress callback, and writes a single summary
+// line to w. An error reported in the stream is returned.
+func (c Config) decodePull(r io.Reader, img string, w io.Writer) error {
+	summary := &pullSummary{
+		image:  img,
+		layers: map[string]int64{},
+	}
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		event := PullEvent{
+			Image:  img,
+			ID:     msg.ID,
+			Status: msg.Status,
+		}
+		if msg.Progress != nil {
+			event.Current = msg.Progress.Current
+			event.Total = msg.Progress.Total
+		}
+		summary.add(event)
+		if c.PullProgress != nil {
+			c.PullProgress(event)
+		}
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+const pullStream = `{"status":"Pulling from ethereum/solc","id":"0.8.28"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"a1"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"b2"}
+{"status":"Downloading","progressDetail":{"current":500000,"total":1000000},"progress":"[=====>     ]","id":"a1"}
+{"status":"Downloading","progressDetail":{"current":1000000,"total":1000000},"progress":"[==========>]","id":"a1"}
+{"status":"Downloading","progressDetail":{"current":1500000,"total":1500000},"progress":"[==========>]","id":"b2"}
+{"status":"Pull complete","progressDetail":{},"id":"a1"}
+{"status":"Pull complete","progressDetail":{},"id":"b2"}
+{"status":"Digest: sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"}
+{"status":"Status: Downloaded newer image for ethereum/solc:0.8.28"}
+`
+
+func TestPullImageProgress(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	engine.PullOutput = pullStream
+
+	var events []shared.PullEvent
+	var stdout bytes.Buffer
+	err := shared.PullImage(context.Background(), engine, "ethereum/solc:0.8.28", shared.PlatformLinuxAMD64(),
+		shared.WithStdout(&stdout),
+		shared.WithPullProgress(func(e shared.PullEvent) { events = append(events, e) }),
+	)
+	assert.NoError(t, err)
+
+	if assert.Len(t, events, 10) {
+		assert.Equal(t, shared.PullEvent{Image: "ethereum/solc:0.8.28", ID: "a1", Status: "Downloading", Current: 500000, Total: 1000000}, events[3])
+	}
+	assert.Equal(t, "pulled ethereum/solc:0.8.28 sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae: 2 layers, 2.5 MB downloaded\n", stdout.String())
+}
+
+func TestPullImageStreamError(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	engine.PullOutput = `{"status":"Pulling from ethereum/solc","id":"0.8.28"}
+{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}
+`
+	err := shared.PullImage(context.Background(), engine, "ethereum/solc:0.8.28", shared.PlatformLinuxAMD64(), shared.WithStdout(&bytes.Buffer{}))
+	assert.ErrorContains(t, err, "manifest unknown")
+}
@@ -0,0 +1,384 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// Mount represents a host path made available to a tool container
+type Mount struct {
+	// Source is the path on the host
+	Source string
+	// Target is the path in the container
+	Target string
+	// ReadOnly prevents the tool from writing to Source
+	ReadOnly bool
+}
+
+// RunSpec represents the specification of a single tool run
+type RunSpec struct {
+	// Package and Func identify the caller in returned errors
+	Package string
+	Func    string
+	// Tool is the name of the tool, e.g. solc, recorded in LabelTool
+	Tool string
+
+	Name     string
+	Image    string
+	Platform DockerPlatformConfig
+	Cmd      []string
+	Mounts   []Mount
+	Env      []string
+	WorkDir  string
+	// User overrides the runner's configured user
+	User string
+	// Stdin, when set, is fed to the tool's standard input, which is
+	// closed once Stdin is exhausted
+	Stdin io.Reader
+	// Stdout, when set, receives the tool's standard output in place of
+	// the runner's configured sinks, e.g. for tools that report results
+	// on it
+	Stdout io.Writer
+}
+
+// Result represents the outcome of a tool run
+type Result struct {
+	ContainerID string
+	ExitCode    int64
+	// ImageDigest is the repository digest of the image the tool ran in,
+	// or "" if it has none, e.g. a locally built image
+	ImageDigest string
+	// Stdout is only recorded when the runner is configured WithCapture
+	Stdout string
+	Stderr string
+}
+
+// killTimeout bounds the calls to kill and remove the container of an
+// interrupted run
+const killTimeout = 10 * time.Second
+
+// Runner triggers containerised tools
+type Runner struct {
+	cli Engine
+	cfg Config
+
+	mu       sync.Mutex
+	rt       EngineRuntime
+	detected bool
+}
+
+// NewRunner instantiate a runner backed by a container engine. By
+// default tool output is written to os.Stdout and os.Stderr.
+func NewRunner(cli Engine, opts ...Option) *Runner {
+	return &Runner{
+		cli: cli,
+		cfg: NewConfig(opts...),
+	}
+}
+
+// Run creates and starts a container as per spec, streams its output and
+// waits for it to stop. Errors are reported as *ToolError. If the tool
+// exits with a non-zero status, the error's Op is OpRunTool and the Result
+// is returned alongside it.
+//
+// Mounts are bind mounted or, for a remote daemon, copied into the
+// container before it starts and, unless read-only, copied back out once
+// the tool exits. See TransferMode.
+//
+// On rootless engines, the container's root already maps to the calling
+// user, so HostUser is not applied. Under SELinux, bind mounts are
+// relabelled for the container. See EngineRuntime.
+//
+// If ctx is done, or the runner's timeout elapses, before the tool exits,
+// the container is killed and the error's Op is OpTimeout.
+//
+// The container is removed once the run is over, whether it succeeded or
+// not, unless the runner is configured WithKeepContainer. A stopped
+// container of this module holding spec.Name, e.g. left behind by a crashed
+// run, is replaced. Any other container holding it is a conflict reported
+// with Op OpCreateContainer.
+func (r *Runner) Run(ctx context.Context, spec RunSpec) (result Result, err error) {
+
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+
+	rt := r.runtime(ctx)
+	copyMounts := r.copyMounts()
+	chown := r.cfg.ChownImage != "" && !copyMounts && !rt.Rootless
+
+	user := spec.User
+	if user == "" && !chown {
+		user = r.cfg.User
+	}
+	user = rt.mapUser(user)
+
+	// The engine refuses copies into a read-only root filesystem, so the
+	// run would fail once its container exists
+	if copyMounts && len(spec.Mounts) > 0 && r.cfg.Sandbox.ReadOnlyRootfs {
+		return Result{}, toolErr(OpCopyToContainer, ErrReadOnlyRootfsCopy, spec.Package, spec.Func)
+	}
+
+	runLabels, err := labels(spec, r.cfg.RunID)
+	if err != nil {
+		return Result{}, CreateContainerErr(err, spec.Package, spec.Func)
+	}
+	containConfig := &container.Config{
+		Image:      spec.Image,
+		Cmd:        spec.Cmd,
+		Env:        spec.Env,
+		WorkingDir: spec.WorkDir,
+		User:       user,
+		Labels:     runLabels,
+	}
+	if spec.Stdin != nil {
+		containConfig.AttachStdin = true
+		containConfig.OpenStdin = true
+		containConfig.StdinOnce = true
+	}
+
+	hostConfig := &container.HostConfig{}
+	for _, m := range spec.Mounts {
+		if copyMounts {
+			break
+		}
+		if rt.SELinux {
+			bind, err := rt.bind(m)
+			if err != nil {
+				return Result{}, CreateContainerErr(err, spec.Package, spec.Func)
+			}
+			hostConfig.Binds = append(hostConfig.Binds, bind)
+			continue
+		}
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   m.Source,
+			Target:   m.Target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+	r.cfg.Sandbox.apply(hostConfig)
+
+	// failCreate reports errors before the container exists, attributing
+	// them to ctx if it is done
+	failCreate := func(op string, err error) *ToolError {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			op, err = OpTimeout, ctxErr
+		}
+		return toolErr(op, err, spec.Package, spec.Func)
+	}
+
+	resp, err := r.cli.ContainerCreate(ctx, containConfig, hostConfig, nil, spec.Platform.OCI(), spec.Name)
+	if errdefs.IsConflict(err) && spec.Name != "" {
+		replaced, rmErr := r.removeStale(ctx, spec.Name)
+		if rmErr != nil {
+			return Result{}, failCreate(OpRemoveContainer, rmErr)
+		}
+		if replaced {
+			resp, err = r.cli.ContainerCreate(ctx, containConfig, hostConfig, nil, spec.Platform.OCI(), spec.Name)
+		}
+	}
+	if err != nil {
+		return Result{}, failCreate(OpCreateContainer, err)
+	}
+	result.ContainerID = resp.ID
+	// The image exists once the container does, so a failed lookup only
+	// leaves the digest unknown
+	result.ImageDigest, _ = ImageDigest(ctx, r.cli, spec.Image)
+	if r.cfg.OnResult != nil {
+		defer func() { r.cfg.OnResult(result) }()
+	}
+
+	// fail reports errors once the container exists. A tool interrupted
+	// by ctx is killed rather than left running.
+	fail := func(op string, err error) *ToolError {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			r.kill(resp.ID)
+			op, err = OpTimeout, ctxErr
+		}
+		e := toolErr(op, err, spec.Package, spec.Func)
+		e.ContainerID = resp.ID
+		return e
+	}
+
+	if !r.cfg.KeepContainer {
+		defer func() {
+			rmErr := r.cleanup(resp.ID)
+			if err == nil && rmErr != nil {
+				err = fail(OpRemoveContainer, rmErr)
+			}
+		}()
+	}
+
+	if copyMounts && len(spec.Mounts) > 0 {
+		if err := r.copyIn(ctx, resp.ID, spec.Mounts, user); err != nil {
+			return result, fail(OpCopyToContainer, err)
+		}
+	}
+
+	var stdin *types.HijackedResponse
+	if spec.Stdin != nil {
+		attach, err := r.cli.ContainerAttach(ctx, resp.ID, container.AttachOptions{Stream: true, Stdin: true})
+		if err != nil {
+			return result, fail(OpAttachContainer, err)
+		}
+		defer attach.Close()
+		stdin = &attach
+	}
+
+	if err := r.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+		return result, fail(OpStartContainer, err)
+	}
+
+	if stdin != nil {
+		if _, err := io.Copy(stdin.Conn, spec.Stdin); err != nil {
+			return result, fail(OpAttachContainer, err)
+		}
+		if err := stdin.CloseWrite(); err != nil {
+			return result, fail(OpAttachContainer, err)
+		}
+	}
+
+	out, err := r.cli.ContainerLogs(ctx, resp.ID, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: true})
+	if err != nil {
+		return result, fail(OpContainerLog, err)
+	}
+	defer out.Close()
+
+	stdoutSink, flushStdout := r.cfg.stdout("container", resp.ID, "image", spec.Image)
+	if spec.Stdout != nil {
+		stdoutSink = spec.Stdout
+	}
+	stderrSink, flushStderr := r.cfg.stderr("container", resp.ID, "image", spec.Image)
+	var stdout, stderr bytes.Buffer
+	if r.cfg.Capture {
+		stdoutSink = io.MultiWriter(stdoutSink, &stdout)
+	}
+	_, err = stdcopy.StdCopy(stdoutSink, io.MultiWriter(stderrSink, &stderr), out)
+	flushStdout()
+	flushStderr()
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if err != nil {
+		return result, fail(OpContainerLog, err)
+	}
+
+	status, err := WaitContainer(ctx, r.cli, resp.ID)
+	if err != nil {
+		return result, fail(OpWaitContainer, err)
+	}
+	result.ExitCode = status
+
+	if copyMounts {
+		if err := r.copyOut(ctx, resp.ID, spec.Mounts); err != nil {
+			return result, fail(OpCopyFromContainer, err)
+		}
+	}
+
+	if chown && r.cfg.User != "" {
+		if err := r.chownOutputs(ctx, spec); err != nil {
+			return result, err
+		}
+	}
+
+	if status != 0 {
+		return result, ToolExitErr(resp.ID, status, result.Stderr, spec.Package, spec.Func)
+	}
+
+	return result, nil
+}
+
+// runtime returns the engine's runtime, detecting it on first use. An
+// engine that cannot be identified is treated as rootful Docker for the
+// run and detection is retried on the next.
+func (r *Runner) runtime(ctx context.Context) EngineRuntime {
+	if r.cfg.Runtime != nil {
+		return *r.cfg.Runtime
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.detected {
+		rt, err := DetectRuntime(ctx, r.cli)
+		if err != nil {
+			return EngineRuntime{}
+		}
+		r.rt, r.detected = rt, true
+	}
+	return r.rt
+}
+
+// removeStale removes the container holding name if it was created by
+// this module and is not running, e.g. left behind by a crashed run. It
+// reports whether the name was freed. A running container, e.g. of a
+// concurrent run, or one created by other means is left alone.
+func (r *Runner) removeStale(ctx context.Context, name string) (bool, error) {
+	containers, err := r.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("name", "^/?"+regexp.QuoteMeta(name)+"$")),
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, c := range containers {
+		if _, ok := c.Labels[LabelManaged]; !ok {
+			continue
+		}
+		if !slices.Contains(staleStates, c.State) {
+			continue
+		}
+		if err := r.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{}); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// kill stops the container of an interrupted run. The container may
+// have exited already, so errors are ignored.
+func (r *Runner) kill(containerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+	r.cli.ContainerKill(ctx, containerID, "KILL")
+}
+
+// cleanup removes the container of a run. The caller's context may
+// already be done, so a fresh one is used, bounded so that a stuck daemon
+// cannot hold up the run.
+func (r *Runner) cleanup(containerID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), killTimeout)
+	defer cancel()
+	return r.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true})
+}
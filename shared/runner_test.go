@@ -0,0 +1,322 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerRun(t *testing.T) {
+	spec := shared.RunSpec{
+		Package:  "shared",
+		Func:     "TestRunnerRun",
+		Name:     "tool",
+		Image:    "narwhal/tool:current",
+		Platform: shared.PlatformLinuxAMD64(),
+		Cmd:      []string{"--version"},
+		Mounts: []shared.Mount{
+			{Source: "/host/in", Target: "/opt/in", ReadOnly: true},
+			{Source: "/host/out", Target: "/opt/out"},
+		},
+	}
+
+	testcases := []struct {
+		script      narwhaltest.Script
+		wantErr     error
+		wantExit    int64
+		wantStderr  string
+		wantRemoved int
+	}{
+		{
+			script:      narwhaltest.Script{Stdout: "ok\n"},
+			wantRemoved: 1,
+		},
+		{
+			script:      narwhaltest.Script{Stderr: "syntax error\n", ExitCode: 1},
+			wantErr:     shared.ErrToolFailed,
+			wantExit:    1,
+			wantStderr:  "syntax error\n",
+			wantRemoved: 1,
+		},
+		{
+			script:  narwhaltest.Script{CreateErr: errors.New("no such image")},
+			wantErr: shared.ErrCreateContainer,
+		},
+		{
+			script:      narwhaltest.Script{StartErr: errors.New("exec format error")},
+			wantErr:     shared.ErrStartContainer,
+			wantRemoved: 1,
+		},
+		{
+			script:      narwhaltest.Script{WaitErr: errors.New("connection reset")},
+			wantErr:     shared.ErrWaitContainer,
+			wantRemoved: 1,
+		},
+	}
+
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine(tc.script)
+		result, err := shared.NewRunner(engine).Run(context.Background(), spec)
+		assert.True(t, errors.Is(err, tc.wantErr), "Case: %d Want: %v Got: %v", i, tc.wantErr, err)
+		assert.Equal(t, tc.wantExit, result.ExitCode, "Case: %d", i)
+		assert.Equal(t, tc.wantStderr, result.Stderr, "Case: %d", i)
+		assert.Len(t, engine.Removed(), tc.wantRemoved, "Case: %d", i)
+
+		creates := engine.Creates()
+		if assert.Len(t, creates, 1, "Case: %d", i) {
+			assert.Equal(t, spec.Cmd, []string(creates[0].Config.Cmd), "Case: %d", i)
+			assert.Equal(t, "linux", creates[0].Platform.OS, "Case: %d", i)
+			assert.Len(t, creates[0].HostConfig.Mounts, 2, "Case: %d", i)
+			assert.True(t, creates[0].HostConfig.Mounts[0].ReadOnly, "Case: %d", i)
+		}
+	}
+}
+
+func TestRunnerRunExitError(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Stderr: "boom", ExitCode: 2})
+	_, err := shared.NewRunner(engine).Run(context.Background(), shared.RunSpec{Image: "tool"})
+
+	var toolErr *shared.ToolError
+	if assert.True(t, errors.As(err, &toolErr)) {
+		assert.Equal(t, shared.OpRunTool, toolErr.Op)
+		assert.Equal(t, int64(2), toolErr.ExitCode)
+		assert.Equal(t, "boom", toolErr.Stderr)
+		assert.Equal(t, "fake-1", toolErr.ContainerID)
+	}
+}
+
+func TestRunnerOutput(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Stdout: "line 1\nline 2", Stderr: "warning\n"})
+
+	var stdout, stderr, logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{}))
+	runner := shared.NewRunner(engine,
+		shared.WithStdout(&stdout),
+		shared.WithStderr(&stderr),
+		shared.WithLogger(logger),
+		shared.WithCapture(),
+	)
+
+	result, err := runner.Run(context.Background(), shared.RunSpec{Image: "tool"})
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2", stdout.String())
+	assert.Equal(t, "warning\n", stderr.String())
+	assert.Equal(t, "line 1\nline 2", result.Stdout)
+	assert.Equal(t, "warning\n", result.Stderr)
+	assert.Equal(t, 3, strings.Count(logs.String(), "msg=\"tool output\""))
+	assert.Contains(t, logs.String(), "line=\"line 2\"")
+	assert.Contains(t, logs.String(), "stream=stderr")
+}
+
+func TestRunnerLogStreamError(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Stdout: "{\"contracts\":", StreamErr: io.ErrUnexpectedEOF})
+	var stdout bytes.Buffer
+
+	_, err := shared.NewRunner(engine).Run(context.Background(), shared.RunSpec{Image: "tool", Stdout: &stdout})
+	assert.True(t, errors.Is(err, shared.ErrContainerLog))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	assert.Equal(t, "{\"contracts\":", stdout.String())
+	assert.Equal(t, []string{"fake-1"}, engine.Removed())
+}
+
+func TestRunnerContainerCleanup(t *testing.T) {
+	spec := shared.RunSpec{Name: "solc", Image: "tool"}
+
+	engine := narwhaltest.NewEngine()
+	_, err := shared.NewRunner(engine, shared.WithKeepContainer()).Run(context.Background(), spec)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fake-1"}, engine.Containers())
+
+	// A rerun with the same name replaces the stale container
+	_, err = shared.NewRunner(engine).Run(context.Background(), spec)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fake-1", "fake-2"}, engine.Removed())
+	assert.Empty(t, engine.Containers())
+}
+
+func TestRunnerNameConflict(t *testing.T) {
+	spec := shared.RunSpec{Name: "solc", Image: "tool"}
+	testcases := []struct {
+		labels  map[string]string
+		running bool
+	}{
+		{
+			// A concurrent run still compiling
+			labels:  map[string]string{shared.LabelManaged: "true"},
+			running: true,
+		},
+		{
+			// A container this module did not create
+			labels: nil,
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		_, err := engine.ContainerCreate(context.Background(), &container.Config{Image: "tool", Labels: tc.labels}, nil, nil, nil, "solc")
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		if tc.running {
+			engine.StartContainer("fake-1")
+		}
+
+		_, err = shared.NewRunner(engine).Run(context.Background(), spec)
+		assert.True(t, errors.Is(err, shared.ErrCreateContainer), fmt.Sprintf("Case: %d Got: %v", i, err))
+		assert.True(t, shared.IsConflict(err), fmt.Sprintf("Case: %d", i))
+		assert.Empty(t, engine.Removed(), fmt.Sprintf("Case: %d", i))
+		assert.Equal(t, []string{"fake-1"}, engine.Containers(), fmt.Sprintf("Case: %d", i))
+	}
+}
+
+func TestRunnerTimeout(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Hang: true})
+	runner := shared.NewRunner(engine, shared.WithTimeout(20*time.Millisecond))
+
+	_, err := runner.Run(context.Background(), shared.RunSpec{Image: "tool"})
+	assert.True(t, errors.Is(err, shared.ErrToolTimeout))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, errors.Is(err, shared.ErrToolFailed))
+	assert.Contains(t, engine.Calls(), "ContainerKill")
+	assert.Equal(t, []string{"fake-1"}, engine.Removed())
+}
+
+func TestRunnerCancelCreate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	engine := narwhaltest.NewEngine(narwhaltest.Script{CreateErr: ctx.Err()})
+
+	_, err := shared.NewRunner(engine).Run(ctx, shared.RunSpec{Image: "tool"})
+	assert.True(t, errors.Is(err, shared.ErrToolTimeout))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, errors.Is(err, shared.ErrCreateContainer))
+}
+
+func TestRunnerCancel(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Hang: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, err := shared.NewRunner(engine, shared.WithKeepContainer()).Run(ctx, shared.RunSpec{Image: "tool"})
+	assert.True(t, errors.Is(err, shared.ErrToolTimeout))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Contains(t, engine.Calls(), "ContainerKill")
+	assert.Equal(t, []string{"fake-1"}, engine.Containers())
+}
+
+func TestRunnerSandbox(t *testing.T) {
+	testcases := []struct {
+		opts        []shared.Option
+		wantNetwork string
+		wantMemory  int64
+		wantRO      bool
+		wantCapDrop []string
+	}{
+		{
+			wantNetwork: "none",
+			wantCapDrop: shared.DefaultSandbox().CapDrop,
+		},
+		{
+			opts: []shared.Option{shared.WithSandbox(shared.Sandbox{
+				CPUs:           2,
+				Memory:         512 << 20,
+				ReadOnlyRootfs: true,
+				CapDrop:        []string{"ALL"},
+				NetworkMode:    shared.NetworkNone,
+			})},
+			wantNetwork: "none",
+			wantMemory:  512 << 20,
+			wantRO:      true,
+			wantCapDrop: []string{"ALL"},
+		},
+		{
+			opts: []shared.Option{shared.WithSandbox(shared.Sandbox{})},
+		},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		_, err := shared.NewRunner(engine, tc.opts...).Run(context.Background(), shared.RunSpec{Image: "tool"})
+		assert.NoError(t, err, "Case: %d", i)
+
+		hc := engine.Creates()[0].HostConfig
+		assert.Equal(t, tc.wantNetwork, string(hc.NetworkMode), "Case: %d", i)
+		assert.Equal(t, tc.wantMemory, hc.Memory, "Case: %d", i)
+		assert.Equal(t, tc.wantRO, hc.ReadonlyRootfs, "Case: %d", i)
+		assert.Equal(t, tc.wantCapDrop, []string(hc.CapDrop), "Case: %d", i)
+	}
+}
+
+func TestRunnerUser(t *testing.T) {
+	spec := shared.RunSpec{
+		Name:   "solc",
+		Image:  "ethereum/solc:0.8.28",
+		Mounts: []shared.Mount{{Source: "/src", Target: "/opt/solidity", ReadOnly: true}, {Source: "/out", Target: "/opt/abi"}},
+	}
+
+	engine := narwhaltest.NewEngine()
+	_, err := shared.NewRunner(engine).Run(context.Background(), spec)
+	assert.NoError(t, err)
+	assert.Equal(t, shared.HostUser(), engine.Creates()[0].Config.User)
+
+	engine = narwhaltest.NewEngine()
+	_, err = shared.NewRunner(engine, shared.WithUser("")).Run(context.Background(), spec)
+	assert.NoError(t, err)
+	assert.Equal(t, "", engine.Creates()[0].Config.User)
+
+	engine = narwhaltest.NewEngine()
+	engine.AddImage(shared.DefaultChownImage, shared.PlatformLinuxAMD64())
+	runner := shared.NewRunner(engine, shared.WithUser("1000:1000"), shared.WithChownOutputs(shared.DefaultChownImage))
+	spec.Platform = shared.PlatformLinuxAMD64()
+	_, err = runner.Run(context.Background(), spec)
+	assert.NoError(t, err)
+	creates := engine.Creates()
+	if assert.Len(t, creates, 2) {
+		assert.Equal(t, "", creates[0].Config.User)
+		assert.Equal(t, "solc-chown", creates[1].Name)
+		assert.Equal(t, shared.DefaultChownImage, creates[1].Config.Image)
+		assert.Equal(t, []string{"chown", "-R", "1000:1000", "/opt/abi"}, []string(creates[1].Config.Cmd))
+		assert.Len(t, creates[1].HostConfig.Mounts, 1)
+	}
+}
+
+func TestRunnerStdin(t *testing.T) {
+	engine := narwhaltest.NewEngine(narwhaltest.Script{Stdout: "result\n"})
+	var stdout, configured bytes.Buffer
+	runner := shared.NewRunner(engine, shared.WithStdout(&configured))
+	result, err := runner.Run(context.Background(), shared.RunSpec{
+		Image:  "ethereum/solc:0.8.28",
+		Stdin:  strings.NewReader("input"),
+		Stdout: &stdout,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "input", engine.Stdin(result.ContainerID))
+	assert.Equal(t, "result\n", stdout.String())
+	assert.Equal(t, "", configured.String())
+	assert.True(t, engine.Creates()[0].Config.StdinOnce)
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"github.com/docker/docker/api/types/container"
+)
+
+// NetworkNone isolates a container from any network
+const NetworkNone = "none"
+
+// Sandbox represents the resource limits and isolation applied to tool
+// containers. Zero values leave the engine defaults in place.
+type Sandbox struct {
+	// CPUs limits the number of CPUs, e.g. 1.5
+	CPUs float64
+	// Memory limits memory in bytes
+	Memory int64
+	// PidsLimit limits the number of processes
+	PidsLimit int64
+	// ReadOnlyRootfs mounts the container's root filesystem read-only.
+	// A tmpfs is mounted on /tmp for scratch files.
+	ReadOnlyRootfs bool
+	// CapDrop lists the Linux capabilities to drop, e.g. ALL
+	CapDrop []string
+	// NetworkMode is the container's network, e.g. NetworkNone
+	NetworkMode string
+	// NoNewPrivileges prevents processes gaining privileges, e.g. via setuid
+	NoNewPrivileges bool
+}
+
+// DefaultSandbox returns the profile applied to tool containers unless
+// configured otherwise. Compilers never need the network, so it is
+// disabled, and capabilities not needed to read inputs and write outputs
+// are dropped.
+func DefaultSandbox() Sandbox {
+	return Sandbox{
+		PidsLimit:       512,
+		CapDrop:         []string{"AUDIT_WRITE", "KILL", "MKNOD", "NET_BIND_SERVICE", "NET_RAW", "SETFCAP", "SETPCAP", "SYS_CHROOT"},
+		NetworkMode:     NetworkNone,
+		NoNewPrivileges: true,
+	}
+}
+
+// apply sets the sandbox on a container's host configuration
+func (s Sandbox) apply(hostConfig *container.HostConfig) {
+	if s.CPUs > 0 {
+		hostConfig.NanoCPUs = int64(s.CPUs * 1e9)
+	}
+	if s.Memory > 0 {
+		hostConfig.Memory = s.Memory
+	}
+	if s.PidsLimit > 0 {
+		pids := s.PidsLimit
+		hostConfig.PidsLimit = &pids
+	}
+	if s.ReadOnlyRootfs {
+		hostConfig.ReadonlyRootfs = true
+		hostConfig.Tmpfs = map[string]string{"/tmp": "rw,noexec,nosuid"}
+	}
+	hostConfig.CapDrop = append(hostConfig.CapDrop, s.CapDrop...)
+	if s.NetworkMode != "" {
+		hostConfig.NetworkMode = container.NetworkMode(s.NetworkMode)
+	}
+	if s.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges:true")
+	}
+}
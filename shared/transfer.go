@@ -0,0 +1,352 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+var (
+	// ErrReadOnlyRootfsCopy represents mounts that cannot be copied into a
+	// container whose root filesystem the sandbox makes read-only
+	ErrReadOnlyRootfsCopy = errors.New("mounts cannot be copied into a read-only root filesystem; use TransferBind or a sandbox without ReadOnlyRootfs")
+)
+
+// TransferMode represents how a run's mounts reach the tool container
+type TransferMode int
+
+const (
+	// TransferAuto bind mounts when the engine's daemon is local and copies
+	// otherwise
+	TransferAuto TransferMode = iota
+	// TransferBind bind mounts host paths into the container
+	TransferBind
+	// TransferCopy copies inputs into the container before the run and
+	// outputs out of it afterwards, which works with remote daemons
+	TransferCopy
+)
+
+func (t TransferMode) String() string {
+	switch t {
+	case TransferAuto:
+		return "Auto"
+	case TransferBind:
+		return "Bind"
+	case TransferCopy:
+		return "Copy"
+	default:
+		return "Unknown"
+	}
+}
+
+// IsLocalDaemon reports whether the daemon at host, as returned by the
+// Docker client's DaemonHost, shares the caller's filesystem. Only unix
+// sockets and Windows named pipes are considered local; TCP and SSH hosts,
+// e.g. a Docker-in-Docker CI sidecar, are not.
+func IsLocalDaemon(host string) bool {
+	return strings.HasPrefix(host, "unix://") || strings.HasPrefix(host, "npipe://")
+}
+
+// copyMounts reports whether the run's mounts should be copied
+func (r *Runner) copyMounts() bool {
+	switch r.cfg.Transfer {
+	case TransferCopy:
+		return true
+	case TransferBind:
+		return false
+	default:
+		return !IsLocalDaemon(r.cli.DaemonHost())
+	}
+}
+
+// copyIn copies the content of every mount into the container, so that
+// outputs already present behave as they would when bind mounted. The
+// archive is streamed, so that large trees are not held in memory.
+func (r *Runner) copyIn(ctx context.Context, containerID string, mounts []Mount, user string) error {
+	pr, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := writeMounts(pw, mounts, user)
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	err := r.cli.CopyToContainer(ctx, containerID, "/", pr, container.CopyToContainerOptions{})
+	// Unblock the writer if the engine stopped reading early
+	pr.CloseWithError(errCopyAborted)
+	if werr := <-written; werr != nil && !errors.Is(werr, errCopyAborted) {
+		return werr
+	}
+	return err
+}
+
+// errCopyAborted stops writing an archive the engine no longer reads
+var errCopyAborted = errors.New("copy to container aborted")
+
+// writeMounts writes a tar archive of every mount, rooted at /, to w
+func writeMounts(w io.Writer, mounts []Mount, user string) error {
+	tw := tar.NewWriter(w)
+	uid, gid := parseUser(user)
+	dirs := map[string]bool{}
+	for _, m := range mounts {
+		target := strings.TrimPrefix(path.Clean(m.Target), "/")
+		if err := writeParents(tw, target, dirs); err != nil {
+			return err
+		}
+		if err := writeTree(tw, m.Source, target, uid, gid); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// copyOut extracts the writable mounts of a run from the container into
+// their host paths
+func (r *Runner) copyOut(ctx context.Context, containerID string, mounts []Mount) error {
+	for _, m := range mounts {
+		if m.ReadOnly {
+			continue
+		}
+		rc, _, err := r.cli.CopyFromContainer(ctx, containerID, m.Target)
+		if err != nil {
+			return err
+		}
+		err = extractTree(rc, m.Source)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeParents adds directory entries for the parents of name
+func writeParents(tw *tar.Writer, name string, written map[string]bool) error {
+	dir := path.Dir(name)
+	if dir == "." || written[dir] {
+		return nil
+	}
+	if err := writeParents(tw, dir, written); err != nil {
+		return err
+	}
+	written[dir] = true
+	return tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     dir + "/",
+		Mode:     0755,
+	})
+}
+
+// writeTree adds the file or directory at src to tw under name, as a bind
+// mount would show it. Symbolic links resolving within src are kept as
+// links; links leading out of it, which would dangle in the container, are
+// replaced by their targets. Special files, e.g. sockets, are rejected.
+func writeTree(tw *tar.Writer, src string, name string, uid int, gid int) error {
+	root, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return err
+	}
+	return writeResolved(tw, root, name, uid, gid, map[string]bool{root: true})
+}
+
+// writeResolved adds the tree at root, a path free of symbolic links, to
+// tw under name. Expanding holds the directories whose links are being
+// replaced by their targets, so that cycles are detected.
+func writeResolved(tw *tar.Writer, root string, name string, uid int, gid int, expanding map[string]bool) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		entry := path.Join(name, filepath.ToSlash(rel))
+		if d.Type()&fs.ModeSymlink != 0 {
+			return writeLink(tw, root, p, entry, uid, gid, expanding)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return writeEntry(tw, p, info, entry, uid, gid)
+	})
+}
+
+// writeLink adds the symbolic link at p to tw under name
+func writeLink(tw *tar.Writer, root string, p string, name string, uid int, gid int, expanding map[string]bool) error {
+	target, err := filepath.EvalSymlinks(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		// A dangling link dangles in a bind mount too
+		linkname, err := os.Readlink(p)
+		if err != nil {
+			return err
+		}
+		return writeSymlink(tw, name, filepath.ToSlash(linkname), uid, gid)
+	}
+	if err != nil {
+		return err
+	}
+
+	if rel, err := filepath.Rel(root, target); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		linkname, err := filepath.Rel(filepath.Dir(p), target)
+		if err != nil {
+			return err
+		}
+		return writeSymlink(tw, name, filepath.ToSlash(linkname), uid, gid)
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return writeEntry(tw, target, info, name, uid, gid)
+	}
+	if expanding[target] {
+		return fmt.Errorf("symbolic link cycle at %s", p)
+	}
+	expanding[target] = true
+	defer delete(expanding, target)
+	return writeResolved(tw, target, name, uid, gid, expanding)
+}
+
+func writeSymlink(tw *tar.Writer, name string, linkname string, uid int, gid int) error {
+	return tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeSymlink,
+		Name:     name,
+		Linkname: linkname,
+		Mode:     0777,
+		Uid:      uid,
+		Gid:      gid,
+	})
+}
+
+// writeEntry adds the regular file or directory at p to tw under name
+func writeEntry(tw *tar.Writer, p string, info fs.FileInfo, name string, uid int, gid int) error {
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return fmt.Errorf("cannot copy %s: unsupported file type %v", p, info.Mode().Type())
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	hdr.Uid, hdr.Gid = uid, gid
+	hdr.Uname, hdr.Gname = "", ""
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// extractTree extracts a tar stream from CopyFromContainer into dst. The
+// stream's entries are rooted at the base name of the copied path, which
+// is stripped.
+func extractTree(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(hdr.Name)
+		i := strings.Index(name, "/")
+		if i < 0 {
+			// The copied path itself
+			if hdr.Typeflag == tar.TypeReg {
+				if err := writeFile(dst, tr, hdr.FileInfo().Mode()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		rel := name[i+1:]
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("illegal path in archive: %s", hdr.Name)
+		}
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, hdr.FileInfo().Mode()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeFile(name string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseUser returns the numeric uid and gid of a uid:gid user, or root
+func parseUser(user string) (int, int) {
+	uidStr, gidStr, _ := strings.Cut(user, ":")
+	uid, err := strconv.Atoi(uidStr)
+	if err != nil {
+		return 0, 0
+	}
+	gid, err := strconv.Atoi(gidStr)
+	if err != nil {
+		gid = uid
+	}
+	return uid, gid
+}
@@ -0,0 +1,241 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paulwizviz/narwhal/narwhaltest"
+	"github.com/paulwizviz/narwhal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLocalDaemon(t *testing.T) {
+	testcases := []struct {
+		input string
+		want  bool
+	}{
+		{input: "unix:///var/run/docker.sock", want: true},
+		{input: "npipe:////./pipe/docker_engine", want: true},
+		{input: "tcp://docker:2376", want: false},
+		{input: "ssh://builder@ci", want: false},
+	}
+	for i, tc := range testcases {
+		got := shared.IsLocalDaemon(tc.input)
+		assert.Equal(t, tc.want, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.want, got))
+	}
+}
+
+func TestRunnerTransferCopy(t *testing.T) {
+	src := t.TempDir()
+	out := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "hello.sol"), []byte("contract Hello {}"), 0644))
+
+	spec := shared.RunSpec{
+		Name:  "solc",
+		Image: "ethereum/solc:0.8.28",
+		Mounts: []shared.Mount{
+			{Source: filepath.Join(src, "hello.sol"), Target: "/opt/solidity/hello.sol", ReadOnly: true},
+			{Source: out, Target: "/opt/abi"},
+		},
+	}
+
+	engine := narwhaltest.NewEngine(narwhaltest.Script{
+		Files: map[string]string{
+			"/opt/abi/Hello.abi":      "[]",
+			"/opt/abi/sub/Hello.bin":  "6080",
+			"/opt/solidity/hello.sol": "ignored",
+		},
+	})
+	engine.Host = "tcp://docker:2376"
+	_, err := shared.NewRunner(engine, shared.WithUser("1000:1000")).Run(context.Background(), spec)
+	assert.NoError(t, err)
+
+	creates := engine.Creates()
+	if assert.Len(t, creates, 1) {
+		assert.Empty(t, creates[0].HostConfig.Mounts)
+	}
+
+	copies := engine.Copies()
+	if assert.Len(t, copies, 1) {
+		assert.Equal(t, "/", copies[0].DstPath)
+		tr := tar.NewReader(bytes.NewReader(copies[0].Content))
+		entries := map[string]string{}
+		uids := map[string]int{}
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			b, _ := io.ReadAll(tr)
+			entries[hdr.Name] = string(b)
+			uids[hdr.Name] = hdr.Uid
+		}
+		assert.Equal(t, "contract Hello {}", entries["opt/solidity/hello.sol"])
+		assert.Contains(t, entries, "opt/abi/")
+		assert.Equal(t, 1000, uids["opt/solidity/hello.sol"])
+		assert.Equal(t, 1000, uids["opt/abi/"])
+	}
+
+	b, err := os.ReadFile(filepath.Join(out, "Hello.abi"))
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(b))
+	b, err = os.ReadFile(filepath.Join(out, "sub", "Hello.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, "6080", string(b))
+}
+
+func TestRunnerTransferCopySymlinks(t *testing.T) {
+	project := t.TempDir()
+	outside := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(project, "src"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(project, "src", "Token.sol"), []byte("contract Token {}"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(project, "node_modules"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(outside, "pkg"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "pkg", "Math.sol"), []byte("library Math {}"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "Vault.sol"), []byte("contract Vault {}"), 0644))
+
+	// A link within the project, a workspace package linked from outside
+	// it and a mount source that is itself a link
+	assert.NoError(t, os.Symlink("src", filepath.Join(project, "contracts")))
+	assert.NoError(t, os.Symlink(filepath.Join(outside, "pkg"), filepath.Join(project, "node_modules", "pkg")))
+	vault := filepath.Join(t.TempDir(), "Vault.sol")
+	assert.NoError(t, os.Symlink(filepath.Join(outside, "Vault.sol"), vault))
+
+	engine := narwhaltest.NewEngine()
+	_, err := shared.NewRunner(engine, shared.WithTransfer(shared.TransferCopy)).Run(context.Background(), shared.RunSpec{
+		Image: "ethereum/solc:0.8.28",
+		Mounts: []shared.Mount{
+			{Source: project, Target: "/opt/project", ReadOnly: true},
+			{Source: vault, Target: "/opt/solidity/Vault.sol", ReadOnly: true},
+		},
+	})
+	assert.NoError(t, err)
+
+	copies := engine.Copies()
+	if assert.Len(t, copies, 1) {
+		tr := tar.NewReader(bytes.NewReader(copies[0].Content))
+		entries := map[string]string{}
+		links := map[string]string{}
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				break
+			}
+			if hdr.Typeflag == tar.TypeSymlink {
+				links[hdr.Name] = hdr.Linkname
+				continue
+			}
+			b, _ := io.ReadAll(tr)
+			entries[hdr.Name] = string(b)
+		}
+		assert.Equal(t, "contract Token {}", entries["opt/project/src/Token.sol"])
+		assert.Equal(t, map[string]string{"opt/project/contracts": "src"}, links)
+		assert.Equal(t, "library Math {}", entries["opt/project/node_modules/pkg/Math.sol"])
+		assert.Equal(t, "contract Vault {}", entries["opt/solidity/Vault.sol"])
+	}
+}
+
+func TestRunnerTransferCopySpecialFile(t *testing.T) {
+	project := t.TempDir()
+	l, err := net.Listen("unix", filepath.Join(project, "agent.sock"))
+	if err != nil {
+		t.Skip("unix sockets are not supported:", err)
+	}
+	defer l.Close()
+
+	engine := narwhaltest.NewEngine()
+	_, err = shared.NewRunner(engine, shared.WithTransfer(shared.TransferCopy)).Run(context.Background(), shared.RunSpec{
+		Image:  "ethereum/solc:0.8.28",
+		Mounts: []shared.Mount{{Source: project, Target: "/opt/project", ReadOnly: true}},
+	})
+	assert.True(t, errors.Is(err, shared.ErrCopyToContainer), fmt.Sprintf("Got: %v", err))
+	assert.Contains(t, err.Error(), "agent.sock")
+	assert.Empty(t, engine.Copies())
+}
+
+func TestRunnerTransferCopyAborted(t *testing.T) {
+	src := t.TempDir()
+	// Larger than the pipe's writes, so that the writer blocks unless the
+	// aborted copy releases it
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "big.bin"), bytes.Repeat([]byte{1}, 1<<20), 0644))
+
+	engine := narwhaltest.NewEngine()
+	engine.CopyErr = io.ErrUnexpectedEOF
+	_, err := shared.NewRunner(engine, shared.WithTransfer(shared.TransferCopy)).Run(context.Background(), shared.RunSpec{
+		Image:  "tool",
+		Mounts: []shared.Mount{{Source: src, Target: "/opt/in", ReadOnly: true}},
+	})
+	assert.True(t, errors.Is(err, shared.ErrCopyToContainer))
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func TestRunnerTransferMode(t *testing.T) {
+	testcases := []struct {
+		host      string
+		mode      shared.TransferMode
+		wantMount bool
+	}{
+		{host: "unix:///var/run/docker.sock", mode: shared.TransferAuto, wantMount: true},
+		{host: "tcp://docker:2376", mode: shared.TransferAuto, wantMount: false},
+		{host: "tcp://docker:2376", mode: shared.TransferBind, wantMount: true},
+		{host: "unix:///var/run/docker.sock", mode: shared.TransferCopy, wantMount: false},
+	}
+	for i, tc := range testcases {
+		engine := narwhaltest.NewEngine()
+		engine.Host = tc.host
+		spec := shared.RunSpec{
+			Image:  "narwhal/tool:current",
+			Mounts: []shared.Mount{{Source: t.TempDir(), Target: "/opt/in", ReadOnly: true}},
+		}
+		_, err := shared.NewRunner(engine, shared.WithTransfer(tc.mode)).Run(context.Background(), spec)
+		assert.NoError(t, err, fmt.Sprintf("Case: %d", i))
+		got := len(engine.Creates()[0].HostConfig.Mounts) > 0
+		assert.Equal(t, tc.wantMount, got, fmt.Sprintf("Case: %d Want: %v Got: %v", i, tc.wantMount, got))
+	}
+}
+
+func TestRunnerTransferCopyReadOnlyRootfs(t *testing.T) {
+	engine := narwhaltest.NewEngine()
+	runner := shared.NewRunner(engine,
+		shared.WithTransfer(shared.TransferCopy),
+		shared.WithSandbox(shared.Sandbox{ReadOnlyRootfs: true}),
+	)
+
+	_, err := runner.Run(context.Background(), shared.RunSpec{
+		Image:  "tool",
+		Mounts: []shared.Mount{{Source: t.TempDir(), Target: "/opt/out"}},
+	})
+	assert.True(t, errors.Is(err, shared.ErrCopyToContainer))
+	assert.True(t, errors.Is(err, shared.ErrReadOnlyRootfsCopy))
+	assert.Empty(t, engine.Creates())
+
+	// Runs without mounts have nothing to copy
+	_, err = runner.Run(context.Background(), shared.RunSpec{Image: "tool"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 The Contributors to narwhal
+// This file is part of the narwhal project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND,
+// either express or implied. See the License for the specific
+// language governing permissions and limitations under the License.
+//
+// For a list of contributors, refer to the CONTRIBUTORS file or the
+// repository's commit history.
+
+package shared
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// DefaultChownImage is the helper image used by WithChownOutputs
+const DefaultChownImage = "busybox:stable"
+
+// HostUser returns the calling user as uid:gid on Linux, so that files
+// written by tools into bind-mounted host paths are owned by the caller.
+// Elsewhere, the engine maps file ownership itself and "" is returned,
+// leaving the image's default user in place.
+func HostUser() string {
+	if runtime.GOOS != "linux" {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+}
+
+// chownOutputs hands the writable mounts of a run over to the configured
+// user, for tool images that cannot run as an arbitrary user
+func (r *Runner) chownOutputs(ctx context.Context, spec RunSpec) error {
+	var mounts []Mount
+	cmd := []string{"chown", "-R", r.cfg.User}
+	for _, m := range spec.Mounts {
+		if m.ReadOnly {
+			continue
+		}
+		mounts = append(mounts, m)
+		cmd = append(cmd, m.Target)
+	}
+	if len(mounts) == 0 {
+		return nil
+	}
+
+	if err := ensureImage(ctx, r.cli, r.cfg.ChownImage, spec.Platform, r.cfg.PullPolicy, r.cfg); err != nil {
+		return PullImageError(err, spec.Package, spec.Func)
+	}
+
+	helper := &Runner{
+		cli: r.cli,
+		cfg: r.cfg,
+	}
+	helper.cfg.User = ""
+	rt := r.runtime(ctx)
+	helper.cfg.Runtime = &rt
+	helper.cfg.ChownImage = ""
+	helper.cfg.OnResult = nil
+	helper.cfg.Sandbox = Sandbox{
+		NetworkMode:     NetworkNone,
+		NoNewPrivileges: true,
+	}
+
+	var name string
+	if spec.Name != "" {
+		name = spec.Name + "-chown"
+	}
+	_, err := helper.Run(ctx, RunSpec{
+		Package:  spec.Package,
+		Func:     spec.Func,
+		Tool:     "chown",
+		Name:     name,
+		Image:    r.cfg.ChownImage,
+		Platform: spec.Platform,
+		Cmd:      cmd,
+		Mounts:   mounts,
+	})
+	return err
+}